@@ -9,15 +9,170 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// AuthMethod selects how the provider authenticates against the Proxmox API.
+// +kubebuilder:validation:Enum=Password;APIToken
+type AuthMethod string
+
+const (
+	// AuthMethodPassword logs in with the "username" and "password" keys of the credentials Secret.
+	AuthMethodPassword AuthMethod = "Password"
+	// AuthMethodAPIToken uses the "tokenID" and "tokenSecret" keys of the credentials Secret.
+	AuthMethodAPIToken AuthMethod = "APIToken"
+)
+
 // ProviderConfigSpec defines the configuration for Proxmox in the ProviderConfig.
+// +kubebuilder:validation:XValidation:rule="!has(self.endpoints) || size(self.endpoints) == 0 || !has(self.tls) || (!has(self.tls.certificateFingerprint) && !has(self.tls.serverName))",message="tls.certificateFingerprint and tls.serverName match a single node and cannot be used with endpoints; trust the cluster CA with a CA bundle instead"
 type ProviderConfigSpec struct {
 	Endpoint    string               `json:"endpoint"`    // Endpoint for the Proxmox API
 	Credentials xpv1.SecretReference `json:"credentials"` // Credentials to connect to Proxmox
+
+	// Endpoints are further members of the Proxmox cluster, tried in order when
+	// Endpoint cannot be reached. Requests return to a preferred endpoint once
+	// it passes a health check again. Each node presents its own certificate,
+	// so they are verified with a CA bundle rather than a fingerprint: the
+	// certificates Proxmox generates are signed by the cluster CA, found in
+	// /etc/pve/pve-root-ca.pem on any node.
+	// +optional
+	Endpoints []string `json:"endpoints,omitempty"`
+
+	// HealthCheckInterval is how often an unreachable preferred endpoint is
+	// checked while another one is in use. Defaults to 1m.
+	// +optional
+	HealthCheckInterval *metav1.Duration `json:"healthCheckInterval,omitempty"`
+
+	// AuthMethod selects the credentials used from the Secret. When omitted, an
+	// API token is used if the Secret contains a "tokenID" key, otherwise the
+	// username and password.
+	// +optional
+	AuthMethod AuthMethod `json:"authMethod,omitempty"`
+
+	// DefaultNode is the Proxmox node used for VirtualMachines that set neither
+	// a node nor scheduling, when Scheduling is not set either.
+	// +optional
+	DefaultNode string `json:"defaultNode,omitempty"`
+
+	// Scheduling picks a node from the cluster resources for VirtualMachines
+	// without a node. When set, it takes precedence over DefaultNode.
+	// +optional
+	Scheduling *Scheduling `json:"scheduling,omitempty"`
+
+	// TLS configures how the Proxmox endpoint certificate is verified.
+	// +optional
+	TLS *TLSConfig `json:"tls,omitempty"`
+
+	// Timeouts bounds how long the provider waits for the Proxmox API.
+	// +optional
+	Timeouts *Timeouts `json:"timeouts,omitempty"`
+
+	// RateLimit throttles the requests sent to the Proxmox API, shared by all
+	// VirtualMachines using this ProviderConfig.
+	// +optional
+	RateLimit *RateLimit `json:"rateLimit,omitempty"`
+
+	// VMIDRange bounds the VMIDs allocated to VirtualMachines that do not set
+	// one. Defaults to the next free VMID of the cluster.
+	// +optional
+	VMIDRange *VMIDRange `json:"vmidRange,omitempty"`
+}
+
+// VMIDRange is an inclusive range of VMIDs.
+// +kubebuilder:validation:XValidation:rule="self.min <= self.max",message="min must not be greater than max"
+type VMIDRange struct {
+	// +kubebuilder:validation:Minimum=100
+	// +kubebuilder:validation:Maximum=999999999
+	Min int `json:"min"`
+
+	// +kubebuilder:validation:Minimum=100
+	// +kubebuilder:validation:Maximum=999999999
+	Max int `json:"max"`
+}
+
+// RateLimit throttles the requests sent to a Proxmox endpoint.
+type RateLimit struct {
+	// RequestsPerSecond is the sustained request rate. Unlimited when omitted.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	RequestsPerSecond int32 `json:"requestsPerSecond,omitempty"`
+
+	// Burst is the number of requests allowed above the rate at once.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	Burst int32 `json:"burst,omitempty"`
+
+	// MaxConcurrentRequests caps the requests in flight at the same time.
+	// Unlimited when omitted.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxConcurrentRequests int32 `json:"maxConcurrentRequests,omitempty"`
+}
+
+// Timeouts bounds the time spent waiting for the Proxmox API.
+type Timeouts struct {
+	// Request bounds a single API request, including reading the response.
+	// Defaults to 30s.
+	// +optional
+	Request *metav1.Duration `json:"request,omitempty"`
+
+	// Operation bounds all the API requests made to observe, create, update or
+	// delete a VirtualMachine in one reconcile. By default only the reconcile
+	// timeout of the controller applies.
+	// +optional
+	Operation *metav1.Duration `json:"operation,omitempty"`
+}
+
+// TLSConfig configures verification of the Proxmox API certificate. By default
+// the certificate must be signed by a CA trusted by the system.
+// +kubebuilder:validation:XValidation:rule="!has(self.certificateFingerprint) || !(has(self.caBundle) || has(self.caBundleSecretRef) || has(self.caBundleConfigMapRef))",message="certificateFingerprint cannot be combined with a CA bundle"
+type TLSConfig struct {
+	// CABundle is a PEM encoded CA bundle used to verify the endpoint.
+	// +optional
+	CABundle string `json:"caBundle,omitempty"`
+
+	// CABundleSecretRef selects a key of a Secret holding a PEM encoded CA bundle.
+	// +optional
+	CABundleSecretRef *xpv1.SecretKeySelector `json:"caBundleSecretRef,omitempty"`
+
+	// CABundleConfigMapRef selects a key of a ConfigMap holding a PEM encoded CA bundle.
+	// +optional
+	CABundleConfigMapRef *ConfigMapKeySelector `json:"caBundleConfigMapRef,omitempty"`
+
+	// CertificateFingerprint pins the SHA-256 fingerprint of the endpoint
+	// certificate, as shown by Proxmox (e.g. "AB:CD:..."). When set, the
+	// certificate is accepted only if it matches, regardless of its issuer,
+	// so it cannot be combined with a CA bundle. It cannot be used with
+	// endpoints, as each node has its own certificate.
+	// +optional
+	CertificateFingerprint string `json:"certificateFingerprint,omitempty"`
+
+	// ServerName overrides the host name used to verify the certificate. It
+	// cannot be used with endpoints, as each node has its own host name.
+	// +optional
+	ServerName string `json:"serverName,omitempty"`
+
+	// InsecureSkipTLSVerify disables certificate verification entirely.
+	// +optional
+	InsecureSkipTLSVerify bool `json:"insecureSkipTLSVerify,omitempty"`
+}
+
+// ConfigMapKeySelector is a reference to a key of a ConfigMap in an arbitrary namespace.
+type ConfigMapKeySelector struct {
+	// Name of the ConfigMap.
+	Name string `json:"name"`
+
+	// Namespace of the ConfigMap.
+	Namespace string `json:"namespace"`
+
+	// Key within the ConfigMap.
+	Key string `json:"key"`
 }
 
 // ProviderConfigStatus represents connection or configuration status.
 type ProviderConfigStatus struct {
 	xpv1.ProviderConfigStatus `json:",inline"`
+
+	// ActiveEndpoint is the endpoint the provider currently sends requests to.
+	// +optional
+	ActiveEndpoint string `json:"activeEndpoint,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -3,36 +3,616 @@ package v1alpha1
 
 import (
 	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// Condition types and reasons reported on a VirtualMachine.
+const (
+	// TypeUpToDate indicates whether the Proxmox config matches the spec.
+	TypeUpToDate xpv1.ConditionType = "UpToDate"
+
+	ReasonConfigInSync xpv1.ConditionReason = "ConfigInSync"
+	ReasonConfigDrift  xpv1.ConditionReason = "ConfigDrift"
+	ReasonRejected     xpv1.ConditionReason = "Rejected"
+
+	// TypePendingReboot indicates whether config changes wait for a reboot of the VM.
+	TypePendingReboot xpv1.ConditionType = "PendingReboot"
+
+	ReasonPendingChanges   xpv1.ConditionReason = "PendingChanges"
+	ReasonNoPendingChanges xpv1.ConditionReason = "NoPendingChanges"
+)
+
+// InSync returns a condition indicating the Proxmox config matches the spec.
+func InSync() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeUpToDate,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonConfigInSync,
+	}
+}
+
+// Drifted returns a condition indicating the Proxmox config differs from the
+// spec, with the differing fields listed in the message.
+func Drifted(diff string) xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeUpToDate,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonConfigDrift,
+		Message:            diff,
+	}
+}
+
+// Rejected returns a condition indicating Proxmox refused to apply the given
+// generation of the spec, because it is invalid or the credentials lack the
+// privileges required.
+func Rejected(generation int64, msg string) xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeUpToDate,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonRejected,
+		Message:            msg,
+		ObservedGeneration: generation,
+	}
+}
+
+// PendingReboot returns a condition indicating config changes are only
+// applied once the VM reboots, with the changes listed in the message.
+func PendingReboot(msg string) xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypePendingReboot,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonPendingChanges,
+		Message:            msg,
+	}
+}
+
+// NoPendingReboot returns a condition indicating the VM runs with its config.
+func NoPendingReboot() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypePendingReboot,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonNoPendingChanges,
+	}
+}
+
+// SchedulingPolicy selects how a node is chosen for a VM that does not set one.
+// +kubebuilder:validation:Enum=LeastLoaded;Spread;BinPack
+type SchedulingPolicy string
+
+const (
+	// SchedulingLeastLoaded picks the node with the lowest combined CPU and memory usage.
+	SchedulingLeastLoaded SchedulingPolicy = "LeastLoaded"
+	// SchedulingSpread picks the node running the fewest VMs.
+	SchedulingSpread SchedulingPolicy = "Spread"
+	// SchedulingBinPack picks the node with the least free memory that still fits the VM.
+	SchedulingBinPack SchedulingPolicy = "BinPack"
+)
+
+// Scheduling configures automatic node selection for VMs without a node.
+type Scheduling struct {
+	// Policy used to rank eligible nodes. Defaults to LeastLoaded.
+	// +optional
+	Policy SchedulingPolicy `json:"policy,omitempty"`
+
+	// MaxCPUUsagePercent excludes nodes whose CPU usage is above this value. Defaults to 90.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	// +optional
+	MaxCPUUsagePercent int `json:"maxCPUUsagePercent,omitempty"`
+}
+
+// NetworkDevice configures a virtual network interface, sent to Proxmox as a
+// netN option such as "virtio=BC:24:11:2E:4F:01,bridge=vmbr0,tag=20".
+type NetworkDevice struct {
+	// Index of the netN option of the device. When empty, the lowest index not
+	// used by another device is assigned and recorded here, so that removing
+	// a device does not renumber the others. A device added to an existing VM
+	// takes the index of the device with its MAC address, else an index the
+	// VM does not use. Set it explicitly when late initialization is not
+	// allowed by the management policies.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=31
+	// +optional
+	Index *int `json:"index,omitempty"`
+
+	// Model of the emulated network card. Defaults to virtio.
+	// +kubebuilder:validation:Enum=e1000;e1000-82540em;e1000-82544gc;e1000-82545em;e1000e;i82551;i82557b;i82559er;ne2k_isa;ne2k_pci;pcnet;rtl8139;virtio;vmxnet3
+	// +kubebuilder:default=virtio
+	// +optional
+	Model string `json:"model,omitempty"`
+
+	// MACAddress of the device. Proxmox generates one when empty.
+	// +kubebuilder:validation:Pattern=`^[0-9A-Fa-f]{2}(:[0-9A-Fa-f]{2}){5}$`
+	// +optional
+	MACAddress string `json:"macAddress,omitempty"`
+
+	// Bridge the device is attached to, e.g. vmbr0.
+	Bridge string `json:"bridge"`
+
+	// VLANTag applied to the traffic of the device.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=4094
+	// +optional
+	VLANTag *int `json:"vlanTag,omitempty"`
+
+	// Firewall enables the Proxmox firewall on the device.
+	// +optional
+	Firewall *bool `json:"firewall,omitempty"`
+
+	// LinkDown disconnects the device, as if the cable was unplugged.
+	// +optional
+	LinkDown *bool `json:"linkDown,omitempty"`
+
+	// Rate limits the bandwidth of the device in MB/s, e.g. "12.5".
+	// +kubebuilder:validation:Pattern=`^[0-9]+(\.[0-9]+)?$`
+	// +optional
+	Rate string `json:"rate,omitempty"`
+
+	// MTU of the device. 1 uses the MTU of the bridge. Only supported by virtio.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65520
+	// +optional
+	MTU int `json:"mtu,omitempty"`
+
+	// Queues is the number of packet queues. Only supported by virtio.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=64
+	// +optional
+	Queues int `json:"queues,omitempty"`
+}
+
+// DiskBus is the controller a disk is attached to.
+// +kubebuilder:validation:Enum=scsi;virtio;sata;ide
+type DiskBus string
+
+const (
+	DiskBusSCSI   DiskBus = "scsi"   // Slots 0-30
+	DiskBusVirtIO DiskBus = "virtio" // Slots 0-15
+	DiskBusSATA   DiskBus = "sata"   // Slots 0-5
+	DiskBusIDE    DiskBus = "ide"    // Slots 0-3
+)
+
+// DetachedDiskPolicy selects what happens to the volume of a disk removed from the spec.
+// +kubebuilder:validation:Enum=Keep;Destroy
+type DetachedDiskPolicy string
+
+const (
+	// DetachedDiskKeep leaves the volume on the VM as an unusedN disk.
+	DetachedDiskKeep DetachedDiskPolicy = "Keep"
+	// DetachedDiskDestroy deletes the volume once it is detached.
+	DetachedDiskDestroy DetachedDiskPolicy = "Destroy"
+)
+
+// Disk configures a disk or CD-ROM drive, sent to Proxmox as a scsiN, virtioN,
+// sataN or ideN option such as "local-lvm:vm-101-disk-0,iothread=1,size=32G".
+// A disk either allocates a new volume of Size on Storage, or attaches an
+// existing Volume.
+// +kubebuilder:validation:XValidation:rule="has(self.volume) || (has(self.storage) && has(self.size))",message="either volume or both storage and size must be set"
+// +kubebuilder:validation:XValidation:rule="!has(self.index) || self.index < {'scsi': 31, 'virtio': 16, 'sata': 6, 'ide': 4}[self.bus]",message="index exceeds the slots of the bus: 0-30 for scsi, 0-15 for virtio, 0-5 for sata and 0-3 for ide"
+type Disk struct {
+	// Bus the disk is attached to. Defaults to scsi.
+	// +kubebuilder:default=scsi
+	// +optional
+	Bus DiskBus `json:"bus,omitempty"`
+
+	// Index of the disk on its bus, e.g. 1 for scsi1. When empty, the lowest
+	// slot of the bus not used by another disk is assigned and recorded here.
+	// A disk added to an existing VM takes the slot of the disk with its
+	// volume, else a slot the VM does not use. Set it explicitly when late
+	// initialization is not allowed by the management policies.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=30
+	// +optional
+	Index *int `json:"index,omitempty"`
+
+	// Storage to allocate a new volume on, e.g. local-lvm.
+	// +optional
+	Storage string `json:"storage,omitempty"`
+
+	// Size of the new volume, e.g. 32G or 512M. A missing unit means GiB.
+	// Increasing it grows the disk while the VM runs; disks cannot shrink.
+	// +kubebuilder:validation:Pattern=`^[0-9]+(\.[0-9]+)?[KMGT]?$`
+	// +optional
+	Size string `json:"size,omitempty"`
+
+	// Volume to attach instead of allocating one, e.g. local-lvm:vm-101-disk-0,
+	// local:iso/debian-12.iso, or none for an empty CD-ROM drive.
+	// +optional
+	Volume string `json:"volume,omitempty"`
+
+	// Media is cdrom for CD-ROM drives. Defaults to disk.
+	// +kubebuilder:validation:Enum=disk;cdrom
+	// +optional
+	Media string `json:"media,omitempty"`
+
+	// Format of a new volume. Defaults to the preferred format of the storage.
+	// +kubebuilder:validation:Enum=raw;qcow2;vmdk
+	// +optional
+	Format string `json:"format,omitempty"`
+
+	// Cache mode of the drive. Defaults to none.
+	// +kubebuilder:validation:Enum=none;writethrough;writeback;unsafe;directsync
+	// +optional
+	Cache string `json:"cache,omitempty"`
+
+	// IOThread runs the I/O of the drive in a dedicated thread.
+	// +optional
+	IOThread *bool `json:"iothread,omitempty"`
+
+	// Discard passes trim requests from the guest to the storage.
+	// +optional
+	Discard *bool `json:"discard,omitempty"`
+
+	// SSD presents the drive to the guest as a solid-state drive.
+	// +optional
+	SSD *bool `json:"ssd,omitempty"`
+
+	// Backup includes the drive in backups. Defaults to true.
+	// +optional
+	Backup *bool `json:"backup,omitempty"`
+}
+
+// CloneSource selects the VM, usually a template, a VM is cloned from.
+// +kubebuilder:validation:XValidation:rule="has(self.vmid) != has(self.name)",message="exactly one of vmid or name must be set"
+type CloneSource struct {
+	// VMID of the template.
+	// +kubebuilder:validation:Minimum=100
+	// +optional
+	VMID int `json:"vmid,omitempty"`
+
+	// Name of the template, which must be unique among the templates of the cluster.
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// Full copies the disks of the template. Linked clones, the default, share
+	// the disks of the template and are only supported for templates.
+	// +optional
+	Full bool `json:"full,omitempty"`
+
+	// Storage for the disks of a full clone. Defaults to the storage of the
+	// disks of the template.
+	// +optional
+	Storage string `json:"storage,omitempty"`
+
+	// Format of the disks of a full clone on file based storages.
+	// +kubebuilder:validation:Enum=raw;qcow2;vmdk
+	// +optional
+	Format string `json:"format,omitempty"`
+}
+
+// IPConfig configures the addresses of a network device through cloud-init,
+// sent to Proxmox as an ipconfigN option such as "ip=10.0.0.5/24,gw=10.0.0.1".
+type IPConfig struct {
+	// Index of the network device, e.g. 0 for net0.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=31
+	Index int `json:"index"`
+
+	// IPv4 is dhcp, or an address in CIDR notation such as 10.0.0.5/24.
+	// +optional
+	IPv4 string `json:"ipv4,omitempty"`
+
+	// GatewayV4 is the IPv4 gateway, used with a static IPv4 address.
+	// +optional
+	GatewayV4 string `json:"gatewayV4,omitempty"`
+
+	// IPv6 is dhcp, auto for SLAAC, or an address in CIDR notation.
+	// +optional
+	IPv6 string `json:"ipv6,omitempty"`
+
+	// GatewayV6 is the IPv6 gateway, used with a static IPv6 address.
+	// +optional
+	GatewayV6 string `json:"gatewayV6,omitempty"`
+}
+
+// CloudInitSnippets reference custom cloud-init files, which replace those
+// Proxmox generates, on a storage with snippets enabled, e.g.
+// local:snippets/user-data.yaml.
+type CloudInitSnippets struct {
+	// +optional
+	User string `json:"user,omitempty"`
+	// +optional
+	Network string `json:"network,omitempty"`
+	// +optional
+	Meta string `json:"meta,omitempty"`
+	// +optional
+	Vendor string `json:"vendor,omitempty"`
+}
+
+// CloudInit configures the first boot of the VM through cloud-init.
+type CloudInit struct {
+	// User created by cloud-init. Defaults to the user of the image.
+	// +optional
+	User string `json:"user,omitempty"`
+
+	// PasswordSecretRef references the password of the user.
+	// +optional
+	PasswordSecretRef *xpv1.SecretKeySelector `json:"passwordSecretRef,omitempty"`
+
+	// SSHKeys are the public keys authorized to log in as the user.
+	// +optional
+	SSHKeys []string `json:"sshKeys,omitempty"`
+
+	// IPConfigs configure the addresses of the network devices.
+	// +listType=map
+	// +listMapKey=index
+	// +optional
+	IPConfigs []IPConfig `json:"ipConfigs,omitempty"`
+
+	// Nameservers are the DNS servers of the VM. Defaults to those of the node.
+	// +optional
+	Nameservers []string `json:"nameservers,omitempty"`
+
+	// SearchDomain is the DNS search domain of the VM. Defaults to that of the node.
+	// +optional
+	SearchDomain string `json:"searchDomain,omitempty"`
+
+	// Snippets replace the cloud-init files Proxmox generates.
+	// +optional
+	Snippets *CloudInitSnippets `json:"snippets,omitempty"`
+
+	// Type of the cloud-init data source. Defaults to nocloud for Linux and
+	// configdrive2 for Windows.
+	// +kubebuilder:validation:Enum=nocloud;configdrive2;opennebula
+	// +optional
+	Type string `json:"type,omitempty"`
+
+	// Storage the cloud-init drive is created on. Defaults to the storage of
+	// the first disk. The drive is added on a free IDE slot, preferring ide2,
+	// unless the VM already has one, e.g. from a template.
+	// +optional
+	Storage string `json:"storage,omitempty"`
+}
+
+// PowerState is the power state of a VM.
+// +kubebuilder:validation:Enum=Running;Stopped;Paused;Suspended
+type PowerState string
+
+const (
+	// PowerStateRunning is a VM that is started.
+	PowerStateRunning PowerState = "Running"
+	// PowerStateStopped is a VM that is shut down.
+	PowerStateStopped PowerState = "Stopped"
+	// PowerStatePaused is a VM whose execution is paused, keeping its memory.
+	PowerStatePaused PowerState = "Paused"
+	// PowerStateSuspended is a VM suspended to disk, which resumes where it
+	// left off when started again.
+	PowerStateSuspended PowerState = "Suspended"
+)
+
+// RebootPolicyType selects when a VM is rebooted to apply pending changes.
+// +kubebuilder:validation:Enum=Never;Automatic;MaintenanceWindow
+type RebootPolicyType string
+
+const (
+	// RebootNever leaves changes pending until the VM is restarted otherwise.
+	RebootNever RebootPolicyType = "Never"
+	// RebootAutomatic reboots the VM as soon as changes are pending.
+	RebootAutomatic RebootPolicyType = "Automatic"
+	// RebootMaintenanceWindow reboots the VM during the next maintenance window.
+	RebootMaintenanceWindow RebootPolicyType = "MaintenanceWindow"
+)
+
+// RebootPolicy selects when a running VM is rebooted to apply config changes
+// Proxmox cannot hot-plug, which it keeps pending until the next start.
+// +kubebuilder:validation:XValidation:rule="self.policy != 'MaintenanceWindow' || has(self.schedule)",message="schedule is required with the MaintenanceWindow policy"
+type RebootPolicy struct {
+	// Policy selects when to reboot. Defaults to Never.
+	// +kubebuilder:default=Never
+	Policy RebootPolicyType `json:"policy"`
+
+	// Schedule opens the maintenance windows, as a cron expression of minute,
+	// hour, day of month, month and day of week evaluated in UTC, e.g.
+	// "0 2 * * 6" for Saturdays at 2:00.
+	// +optional
+	Schedule string `json:"schedule,omitempty"`
+
+	// Duration of a maintenance window. Defaults to 1h.
+	// +optional
+	Duration *metav1.Duration `json:"duration,omitempty"`
+}
+
 // VirtualMachineSpec defines the desired state of VirtualMachine.
 type VirtualMachineSpec struct {
 	ProviderConfigReference          *xpv1.Reference                  `json:"providerConfigReference"` // Link to provider configuration
 	WriteConnectionSecretToReference *xpv1.SecretReference            `json:"writeConnectionSecretToReference,omitempty"`
 	PublishConnectionDetailsTo       *xpv1.PublishConnectionDetailsTo `json:"publishConnectionDetailsTo,omitempty"`
-	DeletionPolicy                   xpv1.DeletionPolicy              `json:"deletionPolicy,omitempty"`
-	ManagementPolicies               xpv1.ManagementPolicies          `json:"managementPolicies,omitempty"`
 
-	VMID    int    `json:"vmid"`    // Unique VM ID in Proxmox
-	Name    string `json:"name"`    // Name of the virtual machine
-	Memory  int    `json:"memory"`  // Memory size in MB
-	Cores   int    `json:"cores"`   // Number of CPU cores
-	CPU     string `json:"cpu"`     // CPU model type (e.g., x86-64-v2-AES)
-	Sockets int    `json:"sockets"` // Number of CPU sockets
-	IDE2    string `json:"ide2"`    // IDE configuration for CD-ROM, formatted as "<path>,media=cdrom"
-	Net0    string `json:"net0"`    // Network configuration, e.g., "virtio,bridge=vmbr0"
-	Numa    bool   `json:"numa"`    // Enable or disable NUMA (converted to 0 or 1 in payload)
-	OSType  string `json:"ostype"`  // OS type (e.g., l26 for Linux)
-	Scsi0   string `json:"scsi0"`   // Primary disk configuration
-	ScsiHW  string `json:"scsihw"`  // SCSI hardware type
+	// DeletionPolicy selects whether the VM is deleted from Proxmox along with
+	// the resource, or orphaned. Defaults to Delete.
+	// +kubebuilder:default=Delete
+	// +optional
+	DeletionPolicy xpv1.DeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// ManagementPolicies are the actions the provider may take on the VM:
+	// Observe, Create, Update, LateInitialize and Delete, or "*" for all of
+	// them. ["Observe"] imports an existing VM read-only; leaving out Delete
+	// orphans the VM when the resource is deleted. Defaults to ["*"].
+	// +kubebuilder:default={"*"}
+	// +optional
+	ManagementPolicies xpv1.ManagementPolicies `json:"managementPolicies,omitempty"`
+
+	// VMID identifies the VM in Proxmox. When empty, a free VMID is allocated
+	// before the VM is created, within the vmidRange of the ProviderConfig if
+	// set, and recorded here and in the crossplane.io/external-name annotation.
+	// +kubebuilder:validation:Minimum=100
+	// +optional
+	VMID int `json:"vmid,omitempty"`
+
+	// Name of the virtual machine. Taken from the VM when it is imported.
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// The following settings are left to Proxmox, or to the template of a
+	// clone, when empty.
+	Memory  int    `json:"memory,omitempty"`  // Memory size in MB
+	Cores   int    `json:"cores,omitempty"`   // Number of CPU cores
+	CPU     string `json:"cpu,omitempty"`     // CPU model type (e.g., x86-64-v2-AES)
+	Sockets int    `json:"sockets,omitempty"` // Number of CPU sockets
+	Numa    *bool  `json:"numa,omitempty"`    // Enable or disable NUMA (converted to 0 or 1 in payload)
+	OSType  string `json:"ostype,omitempty"`  // OS type (e.g., l26 for Linux)
+	ScsiHW  string `json:"scsihw,omitempty"`  // SCSI hardware type
+
+	// NetworkDevices are the network interfaces of the VM, configured as
+	// net0 to net31. When set, network devices not listed are removed from
+	// the VM; when empty, those of the VM, e.g. from a template, are kept.
+	// +kubebuilder:validation:MaxItems=32
+	// +kubebuilder:validation:XValidation:rule="self.all(x, !has(x.index) || self.exists_one(y, has(y.index) && y.index == x.index))",message="network device indexes must be unique"
+	// +optional
+	NetworkDevices []NetworkDevice `json:"networkDevices,omitempty"`
+
+	// Disks are the disks and CD-ROM drives of the VM. When set, disks not
+	// listed are detached from the VM as selected by DetachedDisks; when
+	// empty, those of the VM, e.g. from a template, are kept.
+	// +kubebuilder:validation:MaxItems=56
+	// +kubebuilder:validation:XValidation:rule="self.all(x, !has(x.index) || self.exists_one(y, has(y.index) && y.index == x.index && y.bus == x.bus))",message="disk indexes must be unique on each bus"
+	// +optional
+	Disks []Disk `json:"disks,omitempty"`
 
+	// DetachedDisks selects what happens to a disk removed from the disks
+	// list. Keep, the default, leaves its volume on the VM as an unusedN disk
+	// so that no data is lost. Destroy deletes the volume, as well as any
+	// other unused disk of the VM.
+	// +optional
+	DetachedDisks DetachedDiskPolicy `json:"detachedDisks,omitempty"`
+
+	// Clone creates the VM as a clone of a template instead of an empty VM.
+	// The other fields of the spec are applied to the clone once the clone
+	// task finished. The clone is created on Node, or the scheduled node.
+	// +optional
+	Clone *CloneSource `json:"clone,omitempty"`
+
+	// CloudInit customizes the first boot of the VM. The cloud-init image is
+	// regenerated when these settings change.
+	// +optional
+	CloudInit *CloudInit `json:"cloudInit,omitempty"`
+
+	// Agent enables the QEMU guest agent, through which the addresses and
+	// host name of a running VM are reported. The agent must be installed in
+	// the guest.
+	// +kubebuilder:default=true
+	// +optional
+	Agent *bool `json:"agent,omitempty"`
+
+	// PowerState is the state the VM is kept in. A new VM is started once
+	// created when Running. When empty, the power state is left alone.
+	// +optional
+	PowerState PowerState `json:"powerState,omitempty"`
+
+	// ShutdownTimeout bounds the graceful shutdown of the guest, after which
+	// the VM is stopped hard. Defaults to the Proxmox timeout of 3 minutes.
+	// +optional
+	ShutdownTimeout *metav1.Duration `json:"shutdownTimeout,omitempty"`
+
+	// RebootPolicy selects when a running VM is rebooted to apply config
+	// changes Proxmox keeps pending until the next start. Defaults to Never.
+	// +optional
+	RebootPolicy *RebootPolicy `json:"rebootPolicy,omitempty"`
+
+	// Node is the Proxmox node to create the VM on. When empty, a node is
+	// scheduled, or the ProviderConfig defaultNode is used without scheduling.
+	// +optional
+	Node string `json:"node,omitempty"`
+
+	// Scheduling picks a node from the cluster resources when no node is set.
+	// Fields left empty are taken from the ProviderConfig.
+	// +optional
+	Scheduling *Scheduling `json:"scheduling,omitempty"`
+}
+
+// TaskObservation records an asynchronous Proxmox task started by the provider.
+type TaskObservation struct {
+	UPID      string `json:"upid"`      // Task identifier returned by Proxmox
+	Operation string `json:"operation"` // Operation that started the task: create, update, move, resize, power or delete
+}
+
+// NetworkDeviceObservation is a network device as configured on Proxmox.
+type NetworkDeviceObservation struct {
+	Index      int    `json:"index"`                // Index of the netN option
+	Model      string `json:"model,omitempty"`      // NIC model, e.g. virtio
+	MACAddress string `json:"macAddress,omitempty"` // MAC address, reused if the VM is created again
+	Bridge     string `json:"bridge,omitempty"`     // Bridge the device is attached to
+}
+
+// DiskObservation is a disk as configured on Proxmox.
+type DiskObservation struct {
+	Name   string `json:"name"`             // Config key of the disk, e.g. scsi0 or unused0
+	Volume string `json:"volume,omitempty"` // Volume of the disk, e.g. local-lvm:vm-101-disk-0
+	Size   string `json:"size,omitempty"`   // Size of the volume, e.g. 32G
+}
+
+// GuestInterfaceObservation is a network interface reported by the guest agent.
+type GuestInterfaceObservation struct {
+	Name        string   `json:"name"` // Name in the guest, e.g. eth0
+	MACAddress  string   `json:"macAddress,omitempty"`
+	IPAddresses []string `json:"ipAddresses,omitempty"` // Addresses in CIDR notation, e.g. 10.0.0.5/24
+}
+
+// CloudInitObservation records the cloud-init settings applied to the VM.
+type CloudInitObservation struct {
+	// PasswordSecretVersion is the resource version of the password Secret
+	// last sent to Proxmox, which only reports a placeholder for the password.
+	PasswordSecretVersion string `json:"passwordSecretVersion,omitempty"`
+}
+
+// VirtualMachineObservation holds the state of the VM as observed on Proxmox.
+type VirtualMachineObservation struct {
+	Node string `json:"node,omitempty"` // Node the VM currently runs on, or the node chosen by the scheduler before creation
+
+	// NetworkDevices are the network devices configured on the VM.
+	// +optional
+	NetworkDevices []NetworkDeviceObservation `json:"networkDevices,omitempty"`
+
+	// Disks are the disks configured on the VM, including unused ones.
+	// +optional
+	Disks []DiskObservation `json:"disks,omitempty"`
+
+	// PowerState is the power state of the VM.
+	// +optional
+	PowerState PowerState `json:"powerState,omitempty"`
+
+	// Hostname is the host name of the guest, reported by the guest agent.
+	// +optional
+	Hostname string `json:"hostname,omitempty"`
+
+	// IPAddress is the primary address of the guest, published as the
+	// endpoint of the connection details.
+	// +optional
+	IPAddress string `json:"ipAddress,omitempty"`
+
+	// Interfaces are the network interfaces of the guest, reported by the
+	// guest agent while the VM runs.
+	// +optional
+	Interfaces []GuestInterfaceObservation `json:"interfaces,omitempty"`
+
+	// PendingChanges are the config keys Proxmox applies on the next start
+	// of the VM.
+	// +optional
+	PendingChanges []string `json:"pendingChanges,omitempty"`
+
+	// CloudInit records the cloud-init settings applied to the VM.
+	// +optional
+	CloudInit *CloudInitObservation `json:"cloudInit,omitempty"`
+
+	// Task is the Proxmox task the provider is waiting for, if any.
+	// +optional
+	Task *TaskObservation `json:"task,omitempty"`
 }
 
 // VirtualMachineStatus represents the observed state of the VM.
 type VirtualMachineStatus struct {
 	xpv1.ResourceStatus `json:",inline"`
-	Status              string `json:"status,omitempty"` // Current state of the VM
+	Status              string `json:"status,omitempty"`    // Current state of the VM
+	QMPStatus           string `json:"qmpstatus,omitempty"` // QEMU state of a running VM, e.g. paused
+
+	// AtProvider reports the state of the VM as observed on Proxmox.
+	AtProvider VirtualMachineObservation `json:"atProvider,omitempty"`
 }
 
 // +kubebuilder:object:root=true
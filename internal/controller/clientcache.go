@@ -0,0 +1,108 @@
+package controller
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	"provider-proxmox/internal/proxmoxclient"
+)
+
+// clientIdleTimeout is how long a client unused by any reconcile is kept, so
+// that clients of ProviderConfigs deleted in the meantime are released.
+const clientIdleTimeout = time.Hour
+
+// clientCache shares authenticated Proxmox clients, and their connection pools,
+// between reconciles of all VirtualMachines using the same ProviderConfig.
+type clientCache struct {
+	mu      sync.Mutex
+	clients map[types.UID]*cachedClient
+	now     func() time.Time
+}
+
+// cachedClient is a client along with the ProviderConfig and the version of
+// the configuration it was built from.
+type cachedClient struct {
+	name     string
+	version  string
+	client   *proxmoxclient.ProxmoxClient
+	lastUsed time.Time
+}
+
+func newClientCache() *clientCache {
+	return &clientCache{clients: map[types.UID]*cachedClient{}, now: time.Now}
+}
+
+// clientVersion identifies the ProviderConfig generation and the resource
+// versions of the Secrets and ConfigMaps a client was built from.
+func clientVersion(generation string, resourceVersions ...string) string {
+	return generation + "/" + strings.Join(resourceVersions, "/")
+}
+
+// get returns the client cached for a ProviderConfig if it was built from the
+// given version. A client built from an older version is dropped.
+func (c *clientCache) get(uid types.UID, version string) *proxmoxclient.ProxmoxClient {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.dropIdle()
+
+	cached, ok := c.clients[uid]
+	if !ok {
+		return nil
+	}
+	if cached.version != version {
+		c.drop(uid)
+		return nil
+	}
+	cached.lastUsed = c.now()
+	return cached.client
+}
+
+// put caches the client built for a ProviderConfig. Clients of an earlier
+// ProviderConfig with the same name, deleted and created again, are dropped.
+func (c *clientCache) put(uid types.UID, name, version string, client *proxmoxclient.ProxmoxClient) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.dropIdle()
+
+	for u, cached := range c.clients {
+		if u == uid || cached.name == name {
+			if cached.client != client {
+				cached.client.HTTPClient.CloseIdleConnections()
+			}
+			delete(c.clients, u)
+		}
+	}
+	c.clients[uid] = &cachedClient{name: name, version: version, client: client, lastUsed: c.now()}
+}
+
+// evict drops the client of a ProviderConfig that no longer exists.
+func (c *clientCache) evict(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for uid, cached := range c.clients {
+		if cached.name == name {
+			c.drop(uid)
+		}
+	}
+}
+
+// dropIdle drops the clients not used for clientIdleTimeout. The caller must hold c.mu.
+func (c *clientCache) dropIdle() {
+	for uid, cached := range c.clients {
+		if c.now().Sub(cached.lastUsed) >= clientIdleTimeout {
+			c.drop(uid)
+		}
+	}
+}
+
+// drop removes a client, closing its idle connections. The caller must hold c.mu.
+func (c *clientCache) drop(uid types.UID) {
+	if cached, ok := c.clients[uid]; ok {
+		cached.client.HTTPClient.CloseIdleConnections()
+		delete(c.clients, uid)
+	}
+}
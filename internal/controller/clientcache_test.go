@@ -0,0 +1,51 @@
+package controller
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"provider-proxmox/internal/proxmoxclient"
+)
+
+func TestClientCache(t *testing.T) {
+	now := time.Now()
+	c := newClientCache()
+	c.now = func() time.Time { return now }
+	newClient := func() *proxmoxclient.ProxmoxClient {
+		return &proxmoxclient.ProxmoxClient{HTTPClient: &http.Client{}}
+	}
+
+	a := newClient()
+	c.put("uid-a", "a", "1/10", a)
+	if got := c.get("uid-a", "1/10"); got != a {
+		t.Fatalf("get() = %p, want cached client %p", got, a)
+	}
+	if got := c.get("uid-a", "2/10"); got != nil {
+		t.Errorf("get() returned a client built from an older version")
+	}
+
+	// A ProviderConfig deleted and created again under the same name.
+	c.put("uid-a", "a", "1/10", a)
+	c.put("uid-a2", "a", "1/11", newClient())
+	if got := c.get("uid-a", "1/10"); got != nil {
+		t.Errorf("get() returned the client of a deleted ProviderConfig")
+	}
+
+	c.evict("a")
+	if len(c.clients) != 0 {
+		t.Errorf("evict() kept %d clients", len(c.clients))
+	}
+
+	// Clients unused for a while are released.
+	b := newClient()
+	c.put("uid-b", "b", "1/10", b)
+	now = now.Add(clientIdleTimeout / 2)
+	if got := c.get("uid-b", "1/10"); got != b {
+		t.Fatalf("get() dropped a client in use")
+	}
+	now = now.Add(clientIdleTimeout)
+	if got := c.get("uid-b", "1/10"); got != nil {
+		t.Errorf("get() returned an idle client")
+	}
+}
@@ -0,0 +1,164 @@
+package controller
+
+import (
+	"context"
+	"net/netip"
+	"strconv"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+
+	proxmoxv1alpha1 "provider-proxmox/api/v1alpha1"
+	"provider-proxmox/internal/properties"
+	"provider-proxmox/internal/proxmoxclient"
+)
+
+// connectionHostnameKey is the connection detail holding the host name of the guest.
+const connectionHostnameKey = "hostname"
+
+// agentEnabled reports whether an agent option, e.g. "1" or
+// "enabled=1,fstrim_cloned_disks=1", enables the guest agent.
+func agentEnabled(option string) bool {
+	props := properties.Parse(option)
+	v, ok := props.Get("enabled")
+	if !ok && len(props) > 0 && props[0].Value == "" {
+		v, ok = props[0].Key, true
+	}
+	enabled, _ := properties.ParseBool(v)
+	return ok && enabled
+}
+
+// agentOption returns the agent option enabling or disabling the guest agent,
+// keeping the other agent options of the VM.
+func agentOption(enabled bool, observed string) string {
+	value := properties.FormatBool(enabled)
+	props := properties.Parse(observed)
+	switch {
+	case len(props) == 0:
+		return value
+	case props.Value("enabled") != "":
+		props = props.Set("enabled", value)
+	case props[0].Value == "":
+		props[0].Key = value
+	default:
+		props = append(properties.List{{Key: "enabled", Value: value}}, props...)
+	}
+	return props.String()
+}
+
+// diffAgent compares the agent setting of the spec with the VM config.
+func diffAgent(spec proxmoxv1alpha1.VirtualMachineSpec, cfg map[string]interface{}) []configDiff {
+	observed := configString(cfg, "agent")
+	if spec.Agent == nil || *spec.Agent == agentEnabled(observed) {
+		return nil
+	}
+	return []configDiff{{Key: "agent", Desired: agentOption(*spec.Agent, observed), Observed: observed}}
+}
+
+// observeGuest records the network interfaces and host name reported by the
+// guest agent of a running VM. The last report is kept while the agent does
+// not answer, e.g. during a reboot, and dropped once the VM stops.
+func (e *external) observeGuest(ctx context.Context, vm *proxmoxv1alpha1.VirtualMachine, node string, cfg map[string]interface{}) {
+	at := &vm.Status.AtProvider
+	if at.PowerState != proxmoxv1alpha1.PowerStateRunning || !agentEnabled(configString(cfg, "agent")) {
+		if at.PowerState != proxmoxv1alpha1.PowerStatePaused {
+			at.Interfaces, at.IPAddress, at.Hostname = nil, "", ""
+		}
+		return
+	}
+
+	ifaces, err := e.client.GuestNetworkInterfaces(ctx, node, vm.Spec.VMID)
+	if err != nil {
+		logAgentError(e, vm, err)
+		return
+	}
+	at.Interfaces = guestInterfaces(ifaces)
+	at.IPAddress = primaryAddress(ifaces, at.NetworkDevices)
+
+	hostname, err := e.client.GuestHostname(ctx, node, vm.Spec.VMID)
+	if err != nil {
+		logAgentError(e, vm, err)
+		return
+	}
+	at.Hostname = hostname
+}
+
+func logAgentError(e *external, vm *proxmoxv1alpha1.VirtualMachine, err error) {
+	if proxmoxclient.IsAgentUnavailable(err) {
+		e.log.V(1).Info("Guest agent is not available", "VMID", vm.Spec.VMID, "error", err.Error())
+		return
+	}
+	e.log.Error(err, "Cannot query guest agent", "VMID", vm.Spec.VMID)
+}
+
+// guestInterfaces converts the interfaces reported by the guest agent for the
+// status, leaving out the loopback interface.
+func guestInterfaces(ifaces []proxmoxclient.GuestInterface) []proxmoxv1alpha1.GuestInterfaceObservation {
+	var observed []proxmoxv1alpha1.GuestInterfaceObservation
+	for _, i := range ifaces {
+		if i.Name == "lo" {
+			continue
+		}
+		o := proxmoxv1alpha1.GuestInterfaceObservation{Name: i.Name, MACAddress: i.MACAddress}
+		for _, a := range i.IPAddresses {
+			o.IPAddresses = append(o.IPAddresses, a.Address+"/"+strconv.Itoa(a.Prefix))
+		}
+		observed = append(observed, o)
+	}
+	return observed
+}
+
+// primaryAddress picks the address of the guest to connect to: the first
+// global IPv4 address, else the first global IPv6 address, looking at the
+// interfaces backed by the network devices of the VM in index order first.
+func primaryAddress(ifaces []proxmoxclient.GuestInterface, devices []proxmoxv1alpha1.NetworkDeviceObservation) string {
+	ordered := make([]proxmoxclient.GuestInterface, 0, len(ifaces))
+	used := make([]bool, len(ifaces))
+	for _, d := range devices {
+		for i, iface := range ifaces {
+			if !used[i] && properties.SameMAC(iface.MACAddress, d.MACAddress) {
+				ordered, used[i] = append(ordered, iface), true
+			}
+		}
+	}
+	for i, iface := range ifaces {
+		if !used[i] {
+			ordered = append(ordered, iface)
+		}
+	}
+
+	var v6 string
+	for _, iface := range ordered {
+		for _, a := range iface.IPAddresses {
+			addr, err := netip.ParseAddr(a.Address)
+			if err != nil || !addr.IsGlobalUnicast() {
+				continue
+			}
+			if addr.Is4() {
+				return addr.String()
+			}
+			if v6 == "" {
+				v6 = addr.String()
+			}
+		}
+	}
+	return v6
+}
+
+// connectionDetails returns the details published for connecting to the VM:
+// its primary address and host name, and the cloud-init credentials.
+func connectionDetails(vm *proxmoxv1alpha1.VirtualMachine, password string) managed.ConnectionDetails {
+	details := managed.ConnectionDetails{}
+	add := func(key, value string) {
+		if value != "" {
+			details[key] = []byte(value)
+		}
+	}
+	add(xpv1.ResourceCredentialsSecretEndpointKey, vm.Status.AtProvider.IPAddress)
+	add(connectionHostnameKey, vm.Status.AtProvider.Hostname)
+	if vm.Spec.CloudInit != nil {
+		add(xpv1.ResourceCredentialsSecretUserKey, vm.Spec.CloudInit.User)
+	}
+	add(xpv1.ResourceCredentialsSecretPasswordKey, password)
+	return details
+}
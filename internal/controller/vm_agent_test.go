@@ -0,0 +1,90 @@
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	proxmoxv1alpha1 "provider-proxmox/api/v1alpha1"
+	"provider-proxmox/internal/proxmoxclient"
+)
+
+func TestAgentOption(t *testing.T) {
+	tests := []struct {
+		observed string
+		enabled  bool
+		want     string
+	}{
+		{observed: "", enabled: true, want: "1"},
+		{observed: "0", enabled: true, want: "1"},
+		{observed: "1,fstrim_cloned_disks=1", enabled: false, want: "0,fstrim_cloned_disks=1"},
+		{observed: "enabled=0,type=isa", enabled: true, want: "enabled=1,type=isa"},
+		{observed: "type=isa", enabled: true, want: "enabled=1,type=isa"},
+	}
+	for _, tt := range tests {
+		if got := agentOption(tt.enabled, tt.observed); got != tt.want {
+			t.Errorf("agentOption(%v, %q) = %q, want %q", tt.enabled, tt.observed, got, tt.want)
+		}
+		if got := agentEnabled(agentOption(tt.enabled, tt.observed)); got != tt.enabled {
+			t.Errorf("agentEnabled(agentOption(%v, %q)) = %v", tt.enabled, tt.observed, got)
+		}
+	}
+}
+
+func TestPrimaryAddress(t *testing.T) {
+	lo := proxmoxclient.GuestInterface{Name: "lo", MACAddress: "00:00:00:00:00:00", IPAddresses: []proxmoxclient.GuestIPAddress{
+		{Address: "127.0.0.1", Type: "ipv4", Prefix: 8}, {Address: "::1", Type: "ipv6", Prefix: 128},
+	}}
+	docker := proxmoxclient.GuestInterface{Name: "docker0", MACAddress: "02:42:AC:11:00:01", IPAddresses: []proxmoxclient.GuestIPAddress{
+		{Address: "172.17.0.1", Type: "ipv4", Prefix: 16},
+	}}
+	eth0 := proxmoxclient.GuestInterface{Name: "eth0", MACAddress: "bc:24:11:2e:4f:01", IPAddresses: []proxmoxclient.GuestIPAddress{
+		{Address: "fe80::be24:11ff:fe2e:4f01", Type: "ipv6", Prefix: 64}, {Address: "2001:db8::5", Type: "ipv6", Prefix: 64}, {Address: "10.0.0.5", Type: "ipv4", Prefix: 24},
+	}}
+	v6only := proxmoxclient.GuestInterface{Name: "eth0", MACAddress: "bc:24:11:2e:4f:01", IPAddresses: eth0.IPAddresses[:2]}
+	devices := []proxmoxv1alpha1.NetworkDeviceObservation{{Index: 0, MACAddress: "BC:24:11:2E:4F:01"}}
+
+	tests := []struct {
+		name   string
+		ifaces []proxmoxclient.GuestInterface
+		want   string
+	}{
+		{name: "interface of net0 first", ifaces: []proxmoxclient.GuestInterface{lo, docker, eth0}, want: "10.0.0.5"},
+		{name: "IPv6 only", ifaces: []proxmoxclient.GuestInterface{lo, v6only}, want: "2001:db8::5"},
+		{name: "other interfaces", ifaces: []proxmoxclient.GuestInterface{lo, docker}, want: "172.17.0.1"},
+		{name: "no address", ifaces: []proxmoxclient.GuestInterface{lo}, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := primaryAddress(tt.ifaces, devices); got != tt.want {
+				t.Errorf("primaryAddress() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	want := []proxmoxv1alpha1.GuestInterfaceObservation{{Name: "docker0", MACAddress: "02:42:AC:11:00:01", IPAddresses: []string{"172.17.0.1/16"}}}
+	if got := guestInterfaces([]proxmoxclient.GuestInterface{lo, docker}); !reflect.DeepEqual(got, want) {
+		t.Errorf("guestInterfaces() = %v, want %v", got, want)
+	}
+}
+
+func TestConnectionDetails(t *testing.T) {
+	vm := &proxmoxv1alpha1.VirtualMachine{}
+	vm.Spec.CloudInit = &proxmoxv1alpha1.CloudInit{User: "debian"}
+	vm.Status.AtProvider.IPAddress = "10.0.0.5"
+	vm.Status.AtProvider.Hostname = "web-1"
+
+	got := connectionDetails(vm, "s3cret")
+	want := map[string]string{"endpoint": "10.0.0.5", "hostname": "web-1", "username": "debian", "password": "s3cret"}
+	if len(got) != len(want) {
+		t.Errorf("connectionDetails() = %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if string(got[k]) != v {
+			t.Errorf("connectionDetails()[%q] = %q, want %q", k, got[k], v)
+		}
+	}
+
+	if got := connectionDetails(&proxmoxv1alpha1.VirtualMachine{}, ""); len(got) != 0 {
+		t.Errorf("connectionDetails() = %v for a VM without address or credentials, want none", got)
+	}
+}
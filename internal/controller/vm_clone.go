@@ -0,0 +1,45 @@
+package controller
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	proxmoxv1alpha1 "provider-proxmox/api/v1alpha1"
+	"provider-proxmox/internal/proxmoxclient"
+)
+
+// cloneVM starts cloning the template selected by the spec to a new VM on node,
+// and returns the UPID of the clone task. The other fields of the spec are
+// applied by Update once Observe finds the task finished and the config drifted.
+func (e *external) cloneVM(ctx context.Context, vm *proxmoxv1alpha1.VirtualMachine, node string) (string, error) {
+	source := vm.Spec.Clone
+	vmid := source.VMID
+	var sourceNode string
+	if source.Name != "" {
+		template, err := e.client.FindTemplate(ctx, source.Name)
+		if err != nil {
+			return "", errors.Wrap(err, "cannot find clone source")
+		}
+		vmid, sourceNode = template.VMID, template.Node
+	} else {
+		found, err := e.client.FindVMNode(ctx, vmid)
+		if err != nil {
+			return "", errors.Wrap(err, "cannot find clone source")
+		}
+		sourceNode = found
+	}
+
+	opts := proxmoxclient.CloneOptions{
+		NewID:   vm.Spec.VMID,
+		Name:    vm.Spec.Name,
+		Full:    source.Full,
+		Storage: source.Storage,
+		Format:  source.Format,
+	}
+	if node != sourceNode {
+		opts.Target = node
+	}
+	e.log.Info("Cloning VM", "VMID", vm.Spec.VMID, "Source", vmid, "SourceNode", sourceNode, "Node", node, "Full", source.Full)
+	return e.client.Clone(ctx, sourceNode, vmid, opts)
+}
@@ -0,0 +1,320 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	proxmoxv1alpha1 "provider-proxmox/api/v1alpha1"
+	"provider-proxmox/internal/properties"
+)
+
+// ipConfigKeyRe matches the config keys of cloud-init network settings.
+var ipConfigKeyRe = regexp.MustCompile(`^ipconfig(\d+)$`)
+
+// cloudInitDriveSlots are the slots tried for a new cloud-init drive, in order.
+var cloudInitDriveSlots = []string{"ide2", "ide3", "ide0", "ide1"}
+
+// sensitiveKeys are config keys whose values must not appear in conditions or logs.
+var sensitiveKeys = map[string]bool{"cipassword": true}
+
+// cloudInitOptions returns the desired cloud-init options of the spec by config
+// key. The password is read from its Secret separately, see cloudInitPassword.
+func cloudInitOptions(ci *proxmoxv1alpha1.CloudInit) map[string]string {
+	options := map[string]string{}
+	if ci == nil {
+		return options
+	}
+	set := func(key, value string) {
+		if value != "" {
+			options[key] = value
+		}
+	}
+	set("ciuser", ci.User)
+	set("sshkeys", encodeSSHKeys(ci.SSHKeys))
+	set("nameserver", strings.Join(ci.Nameservers, " "))
+	set("searchdomain", ci.SearchDomain)
+	set("citype", ci.Type)
+	if s := ci.Snippets; s != nil {
+		set("cicustom", propertyString("user", s.User, "network", s.Network, "meta", s.Meta, "vendor", s.Vendor))
+	}
+	for _, c := range ci.IPConfigs {
+		set(fmt.Sprintf("ipconfig%d", c.Index), propertyString("ip", c.IPv4, "gw", c.GatewayV4, "ip6", c.IPv6, "gw6", c.GatewayV6))
+	}
+	return options
+}
+
+// propertyString formats key and value pairs as a property string, skipping
+// empty values.
+func propertyString(pairs ...string) string {
+	var props properties.List
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if pairs[i+1] != "" {
+			props = append(props, properties.Property{Key: pairs[i], Value: pairs[i+1]})
+		}
+	}
+	return props.String()
+}
+
+// diffCloudInit compares the cloud-init options of the spec with the VM config.
+// Options left empty in the spec are not managed, so that those of a template
+// are kept, except network settings when the spec sets any.
+func diffCloudInit(spec proxmoxv1alpha1.VirtualMachineSpec, cfg map[string]interface{}) []configDiff {
+	if spec.CloudInit == nil {
+		return nil
+	}
+	var diffs []configDiff
+	options := cloudInitOptions(spec.CloudInit)
+	keys := make([]string, 0, len(options))
+	for key := range options {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if observed := configString(cfg, key); !cloudInitMatches(key, options[key], observed) {
+			diffs = append(diffs, configDiff{Key: key, Desired: options[key], Observed: observed})
+		}
+	}
+	if len(spec.CloudInit.IPConfigs) > 0 {
+		for _, key := range matchingKeys(cfg, ipConfigKeyRe) {
+			if _, ok := options[key]; !ok {
+				diffs = append(diffs, configDiff{Key: key, Observed: configString(cfg, key)})
+			}
+		}
+	}
+	if key, drive := cloudInitDrive(spec, cfg); key != "" {
+		diffs = append(diffs, configDiff{Key: key, Desired: drive})
+	}
+	return diffs
+}
+
+// cloudInitMatches reports whether an observed cloud-init option equals the
+// desired one, ignoring differences in spelling Proxmox may introduce.
+func cloudInitMatches(key, desired, observed string) bool {
+	switch {
+	case key == "sshkeys":
+		return decodeSSHKeys(desired) == decodeSSHKeys(observed)
+	case key == "nameserver":
+		return strings.Join(strings.Fields(desired), " ") == strings.Join(strings.Fields(observed), " ")
+	case key == "cicustom" || ipConfigKeyRe.MatchString(key):
+		return sameProperties(properties.Parse(desired), properties.Parse(observed))
+	default:
+		return desired == observed
+	}
+}
+
+// sameProperties reports whether two property strings hold the same options,
+// in any order.
+func sameProperties(a, b properties.List) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, p := range a {
+		if v, ok := b.Get(p.Key); !ok || v != p.Value {
+			return false
+		}
+	}
+	return true
+}
+
+// encodeSSHKeys joins public keys one per line and percent-encodes them as
+// Proxmox expects for sshkeys. Every character but the unreserved ones of
+// RFC 3986 is encoded, including spaces, which Proxmox does not accept as "+".
+func encodeSSHKeys(keys []string) string {
+	var lines []string
+	for _, k := range keys {
+		if k = strings.TrimSpace(k); k != "" {
+			lines = append(lines, k)
+		}
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+
+	const hex = "0123456789ABCDEF"
+	var b strings.Builder
+	for _, c := range []byte(strings.Join(lines, "\n")) {
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		default:
+			b.WriteByte('%')
+			b.WriteByte(hex[c>>4])
+			b.WriteByte(hex[c&15])
+		}
+	}
+	return b.String()
+}
+
+// decodeSSHKeys decodes an sshkeys option into one key per line, dropping
+// blank lines, so that differently encoded keys compare equal.
+func decodeSSHKeys(s string) string {
+	if decoded, err := url.PathUnescape(s); err == nil {
+		s = decoded
+	}
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// isCloudInitDrive reports whether a disk option is a cloud-init drive, e.g.
+// "local-lvm:vm-101-cloudinit,media=cdrom".
+func isCloudInitDrive(option string) bool {
+	d, err := properties.ParseDisk(option)
+	return err == nil && strings.Contains(d.File, "cloudinit")
+}
+
+// cloudInitDrive returns the config key and option of the cloud-init drive to
+// add to the VM, or an empty key when the VM has one or does not need one. The
+// drive takes the first IDE slot neither the VM nor the spec uses.
+func cloudInitDrive(spec proxmoxv1alpha1.VirtualMachineSpec, cfg map[string]interface{}) (string, string) {
+	if spec.CloudInit == nil {
+		return "", ""
+	}
+	disks := diskOptions(spec.Disks)
+	for _, key := range matchingKeys(cfg, diskKeyRe) {
+		if isCloudInitDrive(configString(cfg, key)) {
+			return "", ""
+		}
+	}
+	for _, option := range disks {
+		if isCloudInitDrive(option) {
+			return "", ""
+		}
+	}
+	storage := cloudInitStorage(spec, cfg)
+	if storage == "" {
+		return "", ""
+	}
+	for _, key := range cloudInitDriveSlots {
+		if _, ok := disks[key]; !ok && configString(cfg, key) == "" {
+			return key, storage + ":cloudinit"
+		}
+	}
+	return "", ""
+}
+
+// cloudInitStorage returns the storage of the cloud-init drive: the one set in
+// the spec, else that of the first disk of the spec or of the VM.
+func cloudInitStorage(spec proxmoxv1alpha1.VirtualMachineSpec, cfg map[string]interface{}) string {
+	if spec.CloudInit.Storage != "" {
+		return spec.CloudInit.Storage
+	}
+	options := diskOptions(spec.Disks)
+	for _, key := range sortedKeys(options) {
+		if d, err := properties.ParseDisk(options[key]); err == nil && !d.IsCDROM() && d.Storage() != "" {
+			return d.Storage()
+		}
+	}
+	for _, key := range matchingKeys(cfg, diskKeyRe) {
+		if d, err := properties.ParseDisk(configString(cfg, key)); err == nil && !d.IsCDROM() && d.Storage() != "" {
+			return d.Storage()
+		}
+	}
+	return ""
+}
+
+// validateCloudInit checks that the cloud-init drive has a storage and a slot
+// to go on. A clone may take the storage from the disks of its template.
+func validateCloudInit(spec proxmoxv1alpha1.VirtualMachineSpec) error {
+	if spec.CloudInit == nil {
+		return nil
+	}
+	if spec.Clone == nil && cloudInitStorage(spec, nil) == "" {
+		return errors.New("cloud-init needs a storage for its drive; set cloudInit.storage or add a disk")
+	}
+	disks := diskOptions(spec.Disks)
+	for _, option := range disks {
+		if isCloudInitDrive(option) {
+			return nil
+		}
+	}
+	for _, key := range cloudInitDriveSlots {
+		if _, ok := disks[key]; !ok {
+			return nil
+		}
+	}
+	return errors.New("cloud-init needs a free IDE slot for its drive")
+}
+
+// cloudInitPassword reads the cloud-init password from its Secret. It returns
+// an empty password when none is referenced, along with the resource version
+// of the Secret.
+func (e *external) cloudInitPassword(ctx context.Context, vm *proxmoxv1alpha1.VirtualMachine) (string, string, error) {
+	if vm.Spec.CloudInit == nil || vm.Spec.CloudInit.PasswordSecretRef == nil {
+		return "", "", nil
+	}
+	ref := vm.Spec.CloudInit.PasswordSecretRef
+	s := &corev1.Secret{}
+	if err := e.kube.Get(ctx, types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}, s); err != nil {
+		return "", "", errors.Wrap(err, "cannot get cloud-init password secret")
+	}
+	password, ok := s.Data[ref.Key]
+	if !ok || len(password) == 0 {
+		return "", "", errors.Errorf("cloud-init password secret %s/%s has no key %q", ref.Namespace, ref.Name, ref.Key)
+	}
+	return string(password), s.ResourceVersion, nil
+}
+
+// passwordDiff returns the cloud-init password to send when its Secret changed
+// since it was last applied. Proxmox only reports a placeholder for it.
+func passwordDiff(vm *proxmoxv1alpha1.VirtualMachine, cfg map[string]interface{}, password, version string) []configDiff {
+	if password == "" || appliedPasswordVersion(vm) == version {
+		return nil
+	}
+	return []configDiff{{Key: "cipassword", Desired: password, Observed: configString(cfg, "cipassword")}}
+}
+
+func appliedPasswordVersion(vm *proxmoxv1alpha1.VirtualMachine) string {
+	if vm.Status.AtProvider.CloudInit == nil {
+		return ""
+	}
+	return vm.Status.AtProvider.CloudInit.PasswordSecretVersion
+}
+
+// recordPasswordVersion records the version of the password Secret applied to the VM.
+func recordPasswordVersion(vm *proxmoxv1alpha1.VirtualMachine, version string) {
+	if version == "" {
+		return
+	}
+	if vm.Status.AtProvider.CloudInit == nil {
+		vm.Status.AtProvider.CloudInit = &proxmoxv1alpha1.CloudInitObservation{}
+	}
+	vm.Status.AtProvider.CloudInit.PasswordSecretVersion = version
+}
+
+// changesCloudInit reports whether a config update touches the cloud-init
+// image, which Proxmox then needs to regenerate.
+func changesCloudInit(payload map[string]interface{}) bool {
+	for key := range payload {
+		switch {
+		case key == "ciuser", key == "cipassword", key == "sshkeys", key == "nameserver",
+			key == "searchdomain", key == "cicustom", key == "citype", ipConfigKeyRe.MatchString(key):
+			return true
+		}
+	}
+	return false
+}
+
+// redacted returns a copy of a payload that is safe to log.
+func redacted(payload map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(payload))
+	for key, value := range payload {
+		if sensitiveKeys[key] {
+			value = "(redacted)"
+		}
+		out[key] = value
+	}
+	return out
+}
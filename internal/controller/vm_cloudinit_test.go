@@ -0,0 +1,178 @@
+package controller
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	proxmoxv1alpha1 "provider-proxmox/api/v1alpha1"
+)
+
+func TestEncodeSSHKeys(t *testing.T) {
+	keys := []string{
+		"ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIB+x/y= alice@example.com",
+		"",
+		"  ssh-rsa AAAAB3NzaC1yc2E bob@host  ",
+	}
+	want := "ssh-ed25519%20AAAAC3NzaC1lZDI1NTE5AAAAIB%2Bx%2Fy%3D%20alice%40example.com%0Assh-rsa%20AAAAB3NzaC1yc2E%20bob%40host"
+	got := encodeSSHKeys(keys)
+	if got != want {
+		t.Errorf("encodeSSHKeys() = %q, want %q", got, want)
+	}
+	if decoded := decodeSSHKeys(got); decoded != "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIB+x/y= alice@example.com\nssh-rsa AAAAB3NzaC1yc2E bob@host" {
+		t.Errorf("decodeSSHKeys() = %q", decoded)
+	}
+	if got := encodeSSHKeys(nil); got != "" {
+		t.Errorf("encodeSSHKeys(nil) = %q, want empty", got)
+	}
+	// Proxmox may encode fewer characters than we do.
+	if !cloudInitMatches("sshkeys", got, "ssh-ed25519%20AAAAC3NzaC1lZDI1NTE5AAAAIB+x/y=%20alice@example.com%0Assh-rsa%20AAAAB3NzaC1yc2E%20bob@host%0A") {
+		t.Errorf("cloudInitMatches() = false for differently encoded keys")
+	}
+}
+
+func TestCloudInitOptions(t *testing.T) {
+	ci := &proxmoxv1alpha1.CloudInit{
+		User:         "debian",
+		Nameservers:  []string{"10.0.0.53", "1.1.1.1"},
+		SearchDomain: "example.com",
+		Snippets:     &proxmoxv1alpha1.CloudInitSnippets{User: "local:snippets/user.yaml", Vendor: "local:snippets/vendor.yaml"},
+		IPConfigs: []proxmoxv1alpha1.IPConfig{
+			{Index: 0, IPv4: "10.0.0.5/24", GatewayV4: "10.0.0.1", IPv6: "auto"},
+			{Index: 1, IPv4: "dhcp"},
+		},
+	}
+	want := map[string]string{
+		"ciuser":       "debian",
+		"nameserver":   "10.0.0.53 1.1.1.1",
+		"searchdomain": "example.com",
+		"cicustom":     "user=local:snippets/user.yaml,vendor=local:snippets/vendor.yaml",
+		"ipconfig0":    "ip=10.0.0.5/24,gw=10.0.0.1,ip6=auto",
+		"ipconfig1":    "ip=dhcp",
+	}
+	if got := cloudInitOptions(ci); !reflect.DeepEqual(got, want) {
+		t.Errorf("cloudInitOptions() = %v, want %v", got, want)
+	}
+}
+
+func TestDiffCloudInit(t *testing.T) {
+	index := func(i int) *int { return &i }
+	spec := proxmoxv1alpha1.VirtualMachineSpec{
+		Disks: []proxmoxv1alpha1.Disk{{Storage: "local-lvm", Size: "32G"}},
+		CloudInit: &proxmoxv1alpha1.CloudInit{
+			User:      "debian",
+			IPConfigs: []proxmoxv1alpha1.IPConfig{{Index: 0, IPv4: "dhcp"}},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		modify func(*proxmoxv1alpha1.VirtualMachineSpec)
+		cfg    map[string]interface{}
+		want   []string
+	}{
+		{
+			name: "in sync",
+			cfg:  map[string]interface{}{"scsi0": "local-lvm:vm-101-disk-0,size=32G", "ide2": "local-lvm:vm-101-cloudinit,media=cdrom", "ciuser": "debian", "ipconfig0": "ip=dhcp"},
+		},
+		{
+			name: "drive added on ide2",
+			cfg:  map[string]interface{}{"scsi0": "local-lvm:vm-101-disk-0,size=32G", "ciuser": "debian", "ipconfig0": "ip=dhcp"},
+			want: []string{`ide2: want "local-lvm:cloudinit", got ""`},
+		},
+		{
+			name:   "drive skips used slots",
+			modify: func(s *proxmoxv1alpha1.VirtualMachineSpec) { s.CloudInit.Storage = "ceph" },
+			cfg:    map[string]interface{}{"scsi0": "local-lvm:vm-101-disk-0,size=32G", "ide2": "none,media=cdrom", "ciuser": "debian", "ipconfig0": "ip=dhcp"},
+			want:   []string{`ide2: remove "none,media=cdrom"`, `ide3: want "ceph:cloudinit", got ""`},
+		},
+		{
+			name: "drive kept on other slot",
+			modify: func(s *proxmoxv1alpha1.VirtualMachineSpec) {
+				s.Disks = append(s.Disks, proxmoxv1alpha1.Disk{Bus: proxmoxv1alpha1.DiskBusIDE, Index: index(2), Volume: "none", Media: "cdrom"})
+			},
+			cfg: map[string]interface{}{"scsi0": "local-lvm:vm-101-disk-0,size=32G", "ide2": "none,media=cdrom", "ide0": "local-lvm:vm-101-cloudinit,media=cdrom", "ciuser": "debian", "ipconfig0": "ip=dhcp"},
+		},
+		{
+			name: "settings changed",
+			cfg:  map[string]interface{}{"scsi0": "local-lvm:vm-101-disk-0,size=32G", "ide2": "local-lvm:vm-101-cloudinit,media=cdrom", "ciuser": "root", "ipconfig0": "ip=10.0.0.5/24", "ipconfig1": "ip=dhcp"},
+			want: []string{`ciuser: want "debian", got "root"`, `ipconfig0: want "ip=dhcp", got "ip=10.0.0.5/24"`, `ipconfig1: remove "ip=dhcp"`},
+		},
+		{
+			name:   "unset settings left alone",
+			modify: func(s *proxmoxv1alpha1.VirtualMachineSpec) { s.CloudInit.IPConfigs = nil },
+			cfg:    map[string]interface{}{"scsi0": "local-lvm:vm-101-disk-0,size=32G", "ide2": "local-lvm:vm-101-cloudinit,media=cdrom", "ciuser": "debian", "ipconfig0": "ip=dhcp", "searchdomain": "example.com"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := *spec.DeepCopy()
+			if tt.modify != nil {
+				tt.modify(&s)
+			}
+			resolveIndexes(&s, nil)
+			diffs := diffConfig(s, tt.cfg)
+			var got []string
+			for _, d := range diffs {
+				if d.Key != "name" {
+					got = append(got, d.String())
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diffConfig() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPasswordDiff(t *testing.T) {
+	vm := &proxmoxv1alpha1.VirtualMachine{}
+	cfg := map[string]interface{}{"cipassword": "**********"}
+
+	diffs := passwordDiff(vm, cfg, "s3cret", "42")
+	if len(diffs) != 1 || diffs[0].Desired != "s3cret" {
+		t.Fatalf("passwordDiff() = %v, want the password", diffs)
+	}
+	if msg := formatDiffs(diffs); strings.Contains(msg, "s3cret") {
+		t.Errorf("formatDiffs() = %q, leaks the password", msg)
+	}
+	if payload := redacted(updatePayload(cfg, diffs)); payload["cipassword"] == "s3cret" {
+		t.Errorf("redacted() = %v, leaks the password", payload)
+	}
+
+	recordPasswordVersion(vm, "42")
+	if diffs := passwordDiff(vm, cfg, "s3cret", "42"); len(diffs) != 0 {
+		t.Errorf("passwordDiff() = %v after the version was recorded, want none", diffs)
+	}
+	if diffs := passwordDiff(vm, cfg, "n3w", "43"); len(diffs) != 1 {
+		t.Errorf("passwordDiff() = %v after the Secret changed, want the password", diffs)
+	}
+}
+
+func TestValidateCloudInit(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    proxmoxv1alpha1.VirtualMachineSpec
+		wantErr bool
+	}{
+		{name: "no cloud-init", spec: proxmoxv1alpha1.VirtualMachineSpec{}},
+		{name: "storage from disk", spec: proxmoxv1alpha1.VirtualMachineSpec{CloudInit: &proxmoxv1alpha1.CloudInit{}, Disks: []proxmoxv1alpha1.Disk{{Storage: "local-lvm", Size: "8G"}}}},
+		{name: "clone", spec: proxmoxv1alpha1.VirtualMachineSpec{CloudInit: &proxmoxv1alpha1.CloudInit{}, Clone: &proxmoxv1alpha1.CloneSource{Name: "debian"}}},
+		{name: "no storage", spec: proxmoxv1alpha1.VirtualMachineSpec{CloudInit: &proxmoxv1alpha1.CloudInit{}}, wantErr: true},
+		{name: "no free slot", spec: proxmoxv1alpha1.VirtualMachineSpec{CloudInit: &proxmoxv1alpha1.CloudInit{Storage: "local-lvm"}, Disks: []proxmoxv1alpha1.Disk{
+			{Bus: proxmoxv1alpha1.DiskBusIDE, Volume: "none", Media: "cdrom"},
+			{Bus: proxmoxv1alpha1.DiskBusIDE, Volume: "none", Media: "cdrom"},
+			{Bus: proxmoxv1alpha1.DiskBusIDE, Volume: "none", Media: "cdrom"},
+			{Bus: proxmoxv1alpha1.DiskBusIDE, Volume: "none", Media: "cdrom"},
+		}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateCloudInit(tt.spec); (err != nil) != tt.wantErr {
+				t.Errorf("validateCloudInit() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
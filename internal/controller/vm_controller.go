@@ -3,17 +3,22 @@ package controller
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
 	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
 	"github.com/crossplane/crossplane-runtime/pkg/resource"
 
@@ -29,12 +34,18 @@ func (c *VirtualMachineController) SetupWithManager(mgr ctrl.Manager) error {
 		For(&proxmoxv1alpha1.VirtualMachine{}).
 		Complete(managed.NewReconciler(mgr,
 			resource.ManagedKind(proxmoxv1alpha1.VirtualMachineGroupVersionKind),
-			managed.WithExternalConnecter(&connecter{client: mgr.GetClient()}),
+			managed.WithExternalConnecter(&connecter{client: mgr.GetClient(), clients: newClientCache(), vmids: newVMIDReservations()}),
+			// The external name holds the VMID, recorded when the VM is created.
+			managed.WithInitializers(),
+			managed.WithManagementPolicies(),
+			managed.WithFinalizer(newVMFinalizer(mgr.GetClient())),
 		))
 }
 
 type connecter struct {
-	client client.Client
+	client  client.Client
+	clients *clientCache
+	vmids   *vmidReservations
 }
 
 func (c *connecter) Connect(ctx context.Context, mg resource.Managed) (managed.ExternalClient, error) {
@@ -51,6 +62,9 @@ func (c *connecter) Connect(ctx context.Context, mg resource.Managed) (managed.E
 		Name: vm.Spec.ProviderConfigReference.Name,
 	}
 	if err := c.client.Get(ctx, pcName, pc); err != nil {
+		if kerrors.IsNotFound(err) {
+			c.clients.evict(pcName.Name)
+		}
 		return nil, errors.Wrap(err, "cannot get ProviderConfig")
 	}
 
@@ -65,18 +79,181 @@ func (c *connecter) Connect(ctx context.Context, mg resource.Managed) (managed.E
 		return nil, errors.Wrap(err, "cannot get credentials secret")
 	}
 
-	username := string(creds.Data["username"])
-	password := string(creds.Data["password"])
+	tlsOpts, tlsVersions, err := c.tlsOptions(ctx, pc.Spec.TLS)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot load TLS configuration")
+	}
+
+	// Reuse the client of an earlier reconcile unless the configuration changed
+	version := clientVersion(strconv.FormatInt(pc.Generation, 10), append([]string{creds.ResourceVersion}, tlsVersions...)...)
+	client := c.clients.get(pc.UID, version)
+	if client == nil {
+		log.Info("Creating Proxmox client")
+		client, err = newProxmoxClient(ctx, pc, creds, proxmoxclient.Options{
+			TLS:                 tlsOpts,
+			RequestTimeout:      requestTimeout(pc.Spec.Timeouts),
+			Limits:              limitOptions(pc.Spec.RateLimit),
+			Endpoints:           pc.Spec.Endpoints,
+			HealthCheckInterval: healthCheckInterval(pc.Spec.HealthCheckInterval),
+		})
+		if err != nil {
+			return nil, describeError(err, "create Proxmox client")
+		}
+		c.clients.put(pc.UID, pc.Name, version, client)
+	}
+
+	return &external{
+		client:      client,
+		kube:        c.client,
+		log:         log,
+		pc:          pc,
+		vmids:       c.vmids,
+		defaultNode: pc.Spec.DefaultNode,
+		scheduling:  pc.Spec.Scheduling,
+		timeout:     operationTimeout(pc.Spec.Timeouts),
+	}, nil
+}
+
+// requestTimeout returns the per-request timeout of a ProviderConfig, zero
+// selecting the client default.
+func requestTimeout(t *proxmoxv1alpha1.Timeouts) time.Duration {
+	if t == nil || t.Request == nil {
+		return 0
+	}
+	return t.Request.Duration
+}
+
+// healthCheckInterval returns the endpoint health check interval of a
+// ProviderConfig, zero selecting the client default.
+func healthCheckInterval(d *metav1.Duration) time.Duration {
+	if d == nil {
+		return 0
+	}
+	return d.Duration
+}
+
+// limitOptions converts the rate limit of a ProviderConfig for the Proxmox client.
+func limitOptions(l *proxmoxv1alpha1.RateLimit) proxmoxclient.LimitOptions {
+	if l == nil {
+		return proxmoxclient.LimitOptions{}
+	}
+	return proxmoxclient.LimitOptions{
+		RequestsPerSecond: float64(l.RequestsPerSecond),
+		Burst:             int(l.Burst),
+		MaxConcurrent:     int(l.MaxConcurrentRequests),
+	}
+}
 
-	log.Info("Creating Proxmox client")
-	client, err := proxmoxclient.NewClientWithCredentials(pc.Spec.Endpoint, username, password)
-	return &external{client: client, kube: c.client, log: log}, errors.Wrap(err, "cannot create Proxmox client")
+// operationTimeout returns the timeout for a single Observe, Create, Update or
+// Delete, zero meaning that only the reconcile timeout applies.
+func operationTimeout(t *proxmoxv1alpha1.Timeouts) time.Duration {
+	if t == nil || t.Operation == nil {
+		return 0
+	}
+	return t.Operation.Duration
+}
+
+// tlsOptions resolves the TLS settings of a ProviderConfig, reading the CA bundle
+// from a Secret or ConfigMap when referenced. It also returns the resource
+// versions of the objects read.
+func (c *connecter) tlsOptions(ctx context.Context, cfg *proxmoxv1alpha1.TLSConfig) (proxmoxclient.TLSOptions, []string, error) {
+	if cfg == nil {
+		return proxmoxclient.TLSOptions{}, nil, nil
+	}
+	var versions []string
+
+	opts := proxmoxclient.TLSOptions{
+		CABundle:               []byte(cfg.CABundle),
+		CertificateFingerprint: cfg.CertificateFingerprint,
+		ServerName:             cfg.ServerName,
+		InsecureSkipVerify:     cfg.InsecureSkipTLSVerify,
+	}
+
+	if ref := cfg.CABundleSecretRef; ref != nil {
+		s := &corev1.Secret{}
+		if err := c.client.Get(ctx, types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}, s); err != nil {
+			return opts, nil, errors.Wrap(err, "cannot get CA bundle secret")
+		}
+		versions = append(versions, s.ResourceVersion)
+		opts.CABundle = append(append(opts.CABundle, '\n'), s.Data[ref.Key]...)
+	}
+
+	if ref := cfg.CABundleConfigMapRef; ref != nil {
+		cm := &corev1.ConfigMap{}
+		if err := c.client.Get(ctx, types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}, cm); err != nil {
+			return opts, nil, errors.Wrap(err, "cannot get CA bundle config map")
+		}
+		versions = append(versions, cm.ResourceVersion)
+		opts.CABundle = append(append(opts.CABundle, '\n'), cm.Data[ref.Key]...)
+	}
+
+	return opts, versions, nil
+}
+
+// newProxmoxClient authenticates with the method selected by the ProviderConfig,
+// falling back to the keys present in the credentials secret.
+func newProxmoxClient(ctx context.Context, pc *proxmoxv1alpha1.ProviderConfig, creds *corev1.Secret, opts proxmoxclient.Options) (*proxmoxclient.ProxmoxClient, error) {
+	method := pc.Spec.AuthMethod
+	if method == "" {
+		method = proxmoxv1alpha1.AuthMethodPassword
+		if _, ok := creds.Data["tokenID"]; ok {
+			method = proxmoxv1alpha1.AuthMethodAPIToken
+		}
+	}
+
+	switch method {
+	case proxmoxv1alpha1.AuthMethodAPIToken:
+		return proxmoxclient.NewClientWithToken(pc.Spec.Endpoint, string(creds.Data["tokenID"]), string(creds.Data["tokenSecret"]), opts)
+	case proxmoxv1alpha1.AuthMethodPassword:
+		return proxmoxclient.NewClientWithCredentials(ctx, pc.Spec.Endpoint, string(creds.Data["username"]), string(creds.Data["password"]), opts)
+	default:
+		return nil, errors.Errorf("unsupported auth method %q", method)
+	}
 }
 
 type external struct {
-	client *proxmoxclient.ProxmoxClient
-	kube   client.Client //il client Kubernetes per aggiornare i finalizer
-	log    logr.Logger
+	client      *proxmoxclient.ProxmoxClient
+	kube        client.Client //il client Kubernetes per aggiornare i finalizer
+	log         logr.Logger
+	pc          *proxmoxv1alpha1.ProviderConfig // ProviderConfig the client was built from
+	vmids       *vmidReservations               // VMIDs allocated recently, shared by all VMs
+	defaultNode string                          // Node from the ProviderConfig used when the VM does not set one
+	scheduling  *proxmoxv1alpha1.Scheduling     // Scheduling defaults from the ProviderConfig
+	timeout     time.Duration                   // Bounds each Observe, Create, Update and Delete when set
+}
+
+// withTimeout bounds an operation on the VM by the ProviderConfig operation
+// timeout. The reconciler cancels ctx itself on shutdown.
+func (e *external) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if e.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, e.timeout)
+}
+
+// targetNode returns the node a new VM should be created on, if one is given.
+// Scheduling declared on the VM or the ProviderConfig takes precedence over the
+// ProviderConfig default node.
+func (e *external) targetNode(vm *proxmoxv1alpha1.VirtualMachine) string {
+	if vm.Spec.Node != "" {
+		return vm.Spec.Node
+	}
+	if vm.Spec.Scheduling == nil && e.scheduling == nil {
+		return e.defaultNode
+	}
+	return ""
+}
+
+// vmNode returns the node the VM is expected to live on, preferring the last
+// observed one, then the one recorded in the external name.
+func (e *external) vmNode(vm *proxmoxv1alpha1.VirtualMachine) string {
+	if vm.Status.AtProvider.Node != "" {
+		return vm.Status.AtProvider.Node
+	}
+	if node := externalNode(vm); node != "" {
+		return node
+	}
+	return e.targetNode(vm)
 }
 
 func (e *external) Observe(ctx context.Context, mg resource.Managed) (managed.ExternalObservation, error) {
@@ -85,12 +262,98 @@ func (e *external) Observe(ctx context.Context, mg resource.Managed) (managed.Ex
 	if !ok {
 		return managed.ExternalObservation{}, errors.New("managed resource is not a VirtualMachine")
 	}
+	ctx, cancel := e.withTimeout(ctx)
+	defer cancel()
+
+	// Do not resend a create or update Proxmox refused until the spec changes.
+	// An observed-only VM is neither created nor updated.
+	if !meta.WasDeleted(vm) && !observeOnly(vm) {
+		if err := rejection(vm); err != nil {
+			return managed.ExternalObservation{}, err
+		}
+		if err := validateDevices(vm.Spec); err != nil {
+			setRejected(vm, err)
+			return managed.ExternalObservation{}, errors.Wrap(err, "invalid VM spec")
+		}
+		if err := validateRebootPolicy(vm.Spec.RebootPolicy); err != nil {
+			setRejected(vm, err)
+			return managed.ExternalObservation{}, errors.Wrap(err, "invalid VM spec")
+		}
+	}
+
+	// The VMID of an imported VM comes from its external name; a VM without a
+	// VMID has not been created yet.
+	vmidLateInit, err := lateInitVMID(vm)
+	if err != nil {
+		return managed.ExternalObservation{}, err
+	}
+	if vm.Spec.VMID == 0 {
+		return managed.ExternalObservation{ResourceExists: false}, nil
+	}
+
+	// Wait for a create, update or delete started earlier before looking at the VM
+	running, err := e.observeTask(ctx, vm)
+	if err != nil {
+		// A transient API failure says nothing about the VM; requeue and keep its conditions.
+		if !proxmoxclient.IsRetryable(err) {
+			vm.SetConditions(xpv1.Unavailable().WithMessage(err.Error()))
+		}
+		return managed.ExternalObservation{}, err
+	}
+	if running {
+		switch pendingTask(vm) {
+		case taskOpCreate:
+			vm.SetConditions(xpv1.Creating())
+		case taskOpDelete:
+			vm.SetConditions(xpv1.Deleting())
+		}
+		return managed.ExternalObservation{
+			ResourceExists:   true,
+			ResourceUpToDate: true,
+		}, nil
+	}
 
 	// Usa il client Proxmox per ottenere lo stato attuale della VM
-	existing, err := e.client.GetVMStatus(ctx, vm.Spec.VMID)
+	node := e.vmNode(vm)
+	var existing *proxmoxv1alpha1.VirtualMachineStatus
+	if node != "" {
+		existing, err = e.client.GetVMStatus(ctx, node, vm.Spec.VMID)
+	}
+
+	// The VM may have been moved by HA or by hand; look it up across the cluster.
+	if node == "" || proxmoxclient.IsNotFound(err) {
+		found, findErr := e.client.FindVMNode(ctx, vm.Spec.VMID)
+		if findErr == nil {
+			if found != node {
+				e.log.Info("VM found on a different node", "VMID", vm.Spec.VMID, "Node", found)
+			}
+			node = found
+			existing, err = e.client.GetVMStatus(ctx, node, vm.Spec.VMID)
+		} else if !proxmoxclient.IsNotFound(findErr) {
+			return managed.ExternalObservation{}, errors.Wrap(findErr, "error looking up VM in the Proxmox cluster")
+		}
+	}
+
+	// Only a VM Proxmox reports as missing is gone; any other failure, such as a
+	// timeout or an authorization error, says nothing about it and is retried.
+	if err != nil && !proxmoxclient.IsNotFound(err) {
+		return managed.ExternalObservation{}, describeError(err, "check VM status on Proxmox")
+	}
+
+	// The VM was not found, either on its node or anywhere in the cluster.
+	if err != nil || existing == nil {
+		if meta.WasDeleted(vm) {
+			// The VM is gone from Proxmox; release the resource.
+			e.log.Info("VM no longer exists in Proxmox", "VMID", vm.Spec.VMID)
+			if HasFinalizer(vm, finalizerName) {
+				RemoveFinalizer(vm, finalizerName)
+				if err := e.kube.Update(ctx, vm); err != nil {
+					return managed.ExternalObservation{}, errors.Wrap(err, "failed to remove finalizer after VM deletion")
+				}
+			}
+			return managed.ExternalObservation{ResourceExists: false}, nil
+		}
 
-	// If `existing` is nil, treat it as a non-existent VM
-	if proxmoxclient.IsNotFound(err) || existing == nil {
 		e.log.Info("VM not found on Proxmox; creation needed", "VMID", vm.Spec.VMID)
 		return managed.ExternalObservation{
 			ResourceExists:   false,
@@ -98,32 +361,119 @@ func (e *external) Observe(ctx context.Context, mg resource.Managed) (managed.Ex
 		}, nil
 	}
 
-	if err != nil {
-		return managed.ExternalObservation{}, errors.Wrap(err, "error checking VM status on Proxmox")
+	// Leave alone a VM someone else created with the VMID of the spec, unless
+	// it is only observed, which adopts it.
+	if !ownsVM(vm) && !observeOnly(vm) {
+		if meta.WasDeleted(vm) {
+			e.log.Info("VMID is used by another VM; not deleting it", "VMID", vm.Spec.VMID, "Node", node)
+			return managed.ExternalObservation{ResourceExists: false}, nil
+		}
+		err := vmidConflict(vm, node)
+		setRejected(vm, err)
+		return managed.ExternalObservation{}, err
 	}
 
-	// Update the VM status fields with current data from Proxmox
-	vm.Status.Status = existing.Status
+	// Keep the node and VMID of the VM in the external name, also for VMs
+	// created before it recorded them, or moved to another node since.
+	if name := externalName(node, vm.Spec.VMID); meta.GetExternalName(vm) != name {
+		meta.SetExternalName(vm, name)
+		vmidLateInit = true
+	}
 
-	// Add finalizer if it’s missing
-	if !HasFinalizer(vm, finalizerName) {
+	// Add finalizer if it’s missing. This is done before touching the status,
+	// since updating the object overwrites it with the stored version.
+	if !HasFinalizer(vm, finalizerName) && !meta.WasDeleted(vm) && !observeOnly(vm) {
 		AddFinalizer(vm, finalizerName)
 		if err := e.kube.Update(ctx, vm); err != nil {
 			return managed.ExternalObservation{}, errors.Wrap(err, "cannot add finalizer")
 		}
 	}
 
-	// Add finalizer if it’s missing
-	if !HasFinalizer(vm, finalizerName) {
-		AddFinalizer(vm, finalizerName)
-		if err := e.kube.Update(ctx, vm); err != nil {
-			return managed.ExternalObservation{}, errors.Wrap(err, "cannot add finalizer")
+	// Update the VM status fields with current data from Proxmox
+	vm.Status.Status = existing.Status
+	vm.Status.AtProvider.Node = node
+	if !meta.WasDeleted(vm) {
+		vm.SetConditions(xpv1.Available())
+	}
+
+	// Compare the live configuration with the desired spec
+	config, err := e.client.GetVMConfig(ctx, node, vm.Spec.VMID)
+	if err != nil {
+		return managed.ExternalObservation{}, describeError(err, "read VM config from Proxmox")
+	}
+
+	vm.Status.AtProvider.PowerState = observedPowerState(existing, config)
+	vm.Status.AtProvider.NetworkDevices = observeNetworks(config)
+	vm.Status.AtProvider.Disks = observeDisks(config)
+	e.observeGuest(ctx, vm, node, config)
+
+	// The spec of an observed-only VM follows its live config, so that it can
+	// be imported read-only and managed later on.
+	if observeOnly(vm) && !meta.WasDeleted(vm) {
+		if err := e.importVM(ctx, vm, node, config, vmidLateInit); err != nil {
+			return managed.ExternalObservation{}, err
+		}
+		vmidLateInit = false
+	}
+
+	// Match the devices of the spec without an index to those of the VM. The
+	// indexes are recorded only when the policies allow late initialization;
+	// the diff does not depend on them.
+	spec := &vm.Spec
+	lateInit := managementPolicies(vm).ShouldLateInitialize()
+	if !lateInit {
+		spec = vm.Spec.DeepCopy()
+	}
+	indexed, err := resolveIndexes(spec, config)
+	if err != nil && !meta.WasDeleted(vm) {
+		setRejected(vm, err)
+		return managed.ExternalObservation{}, errors.Wrap(err, "invalid VM spec")
+	}
+	lateInit = lateInit && (indexed || vmidLateInit)
+
+	// Proxmox cannot shrink disks; refuse the spec instead of retrying the update.
+	if _, err := diskResizes(*spec, config); err != nil && !meta.WasDeleted(vm) {
+		setRejected(vm, err)
+		return managed.ExternalObservation{}, errors.Wrap(err, "invalid VM spec")
+	}
+
+	diffs, _, err := e.diff(ctx, vm, *spec, config)
+	if err != nil {
+		return managed.ExternalObservation{}, err
+	}
+	msg := formatDiffs(diffs)
+
+	var details managed.ConnectionDetails
+	if !meta.WasDeleted(vm) {
+		password, _, err := e.cloudInitPassword(ctx, vm)
+		if err != nil {
+			return managed.ExternalObservation{}, err
+		}
+		details = connectionDetails(vm, password)
+
+		reboot, err := e.observePending(ctx, vm, node, time.Now())
+		if err != nil {
+			return managed.ExternalObservation{}, describeError(err, "read pending VM changes")
 		}
+		for _, m := range []string{powerDiff(vm), reboot} {
+			if m != "" {
+				msg = strings.TrimPrefix(msg+"; "+m, "; ")
+			}
+		}
+	}
+	if msg != "" {
+		e.log.V(1).Info("VM differs from spec; update needed", "VMID", vm.Spec.VMID, "diff", msg)
+		vm.SetConditions(proxmoxv1alpha1.Drifted(msg))
+	} else {
+		vm.SetConditions(proxmoxv1alpha1.InSync())
 	}
 
 	return managed.ExternalObservation{
-		ResourceExists:   true,
-		ResourceUpToDate: true, //implementare una logica per verificare se è aggiornato rispetto alla specifica
+		ResourceExists:          true,
+		ResourceUpToDate:        msg == "",
+		ResourceLateInitialized: lateInit,
+		Diff:                    msg,
+		ConnectionDetails:       details,
 	}, nil
 }
 
@@ -140,37 +490,161 @@ func (e *external) Create(ctx context.Context, mg resource.Managed) (managed.Ext
 	if !ok {
 		return managed.ExternalCreation{}, errors.New("managed resource is not a VirtualMachine")
 	}
+	ctx, cancel := e.withTimeout(ctx)
+	defer cancel()
+
+	node := e.targetNode(vm)
+	if node == "" {
+		// Reuse a node chosen by an earlier attempt so retries stay on the same node.
+		node = vm.Status.AtProvider.Node
+	}
+	if node == "" {
+		scheduled, err := e.scheduleNode(ctx, vm)
+		if err != nil {
+			return managed.ExternalCreation{}, errors.Wrap(err, "cannot schedule VM")
+		}
+		e.log.Info("Scheduled VM on node", "VMID", vm.Spec.VMID, "Node", scheduled, "Policy", e.schedulingFor(vm).Policy)
+		node = scheduled
+
+		// Record the choice before creating, as the reconciler does not persist
+		// status changes made during Create.
+		vm.Status.AtProvider.Node = node
+		if err := e.kube.Status().Update(ctx, vm); err != nil {
+			return managed.ExternalCreation{}, errors.Wrap(err, "cannot record scheduled node")
+		}
+	}
+
+	// Record the indexes of the devices before they exist, so that removing
+	// a device from the spec later on does not renumber the others.
+	indexed := false
+	if managementPolicies(vm).ShouldLateInitialize() {
+		indexed, _ = resolveIndexes(&vm.Spec, nil)
+	}
+	allocated, err := e.recordVMID(ctx, vm, node, indexed)
+	if err != nil {
+		return managed.ExternalCreation{}, err
+	}
 
-	e.log.Info("Preparing VM creation payload", "VMID", vm.Spec.VMID, "Name", vm.Spec.Name)
+	e.log.Info("Preparing VM creation payload", "VMID", vm.Spec.VMID, "Name", vm.Spec.Name, "Node", node)
 	vm.SetConditions(xpv1.Creating()) // Imposta lo stato di creazione una sola volta
 
+	password, version, err := e.cloudInitPassword(ctx, vm)
+	if err != nil {
+		return managed.ExternalCreation{}, err
+	}
+
+	var upid string
+	if vm.Spec.Clone != nil {
+		// The spec, including cloud-init, is applied to the clone by Update.
+		upid, err = e.cloneVM(ctx, vm, node)
+	} else {
+		payload := createPayload(vm)
+		if password != "" {
+			payload["cipassword"] = password
+		}
+		upid, err = e.client.Create(ctx, node, payload)
+		if err == nil {
+			recordPasswordVersion(vm, version)
+		}
+	}
+	if err != nil {
+		e.log.Error(err, "Failed to create VM")
+		if proxmoxclient.IsConflict(err) {
+			// The reconciler persists the object after a failed create.
+			forgetVMID(vm, allocated)
+		}
+		err = describeError(err, "create VM")
+		if reject(vm, err) {
+			// Persist the rejection; the reconciler does not keep status changes made during Create.
+			if updateErr := e.kube.Status().Update(ctx, vm); updateErr != nil {
+				e.log.Error(updateErr, "Cannot record VM creation failure", "VMID", vm.Spec.VMID)
+			}
+		}
+		return managed.ExternalCreation{}, err
+	}
+
+	// Record the creation task; the reconciler does not persist status changes made during Create.
+	trackTask(vm, upid, taskOpCreate)
+	if err := e.kube.Status().Update(ctx, vm); err != nil {
+		return managed.ExternalCreation{}, errors.Wrap(err, "cannot record VM creation task")
+	}
+
+	e.log.Info("VM creation initiated successfully", "VMID", vm.Spec.VMID, "UPID", upid)
+	return managed.ExternalCreation{ConnectionDetails: connectionDetails(vm, password)}, nil
+}
+
+// createPayload builds the parameters of a new VM from the spec.
+func createPayload(vm *proxmoxv1alpha1.VirtualMachine) map[string]interface{} {
 	payload := map[string]interface{}{
 		"vmid":    vm.Spec.VMID,
 		"name":    vm.Spec.Name,
-		"memory":  vm.Spec.Memory,
-		"cores":   vm.Spec.Cores,
+		"memory":  intString(vm.Spec.Memory),
+		"cores":   intString(vm.Spec.Cores),
 		"cpu":     vm.Spec.CPU,
-		"sockets": vm.Spec.Sockets,
-		"ide2":    vm.Spec.IDE2,
-		"net0":    vm.Spec.Net0,
-		"numa":    boolToProxmoxString(vm.Spec.Numa),
+		"sockets": intString(vm.Spec.Sockets),
+		"numa":    vm.Spec.Numa,
 		"ostype":  vm.Spec.OSType,
-		"scsi0":   vm.Spec.Scsi0,
 		"scsihw":  vm.Spec.ScsiHW,
+		"agent":   boolToProxmoxString(vm.Spec.Agent),
 	}
+	for key, option := range networkOptions(vm.Spec.NetworkDevices, observedMACs(vm.Status.AtProvider.NetworkDevices)) {
+		payload[key] = option
+	}
+	for key, option := range diskOptions(vm.Spec.Disks) {
+		payload[key] = option
+	}
+	for key, option := range cloudInitOptions(vm.Spec.CloudInit) {
+		payload[key] = option
+	}
+	if key, drive := cloudInitDrive(vm.Spec, nil); key != "" {
+		payload[key] = drive
+	}
+	return payload
+}
 
-	if err := e.client.Create(payload); err != nil {
-		e.log.Error(err, "Failed to create VM")
-		return managed.ExternalCreation{}, errors.Wrap(err, "cannot create VM")
+// diff compares the spec of the VM, with the indexes of its devices resolved,
+// with its live config, including the cloud-init
+// password, which Proxmox does not report. It also returns the version of the
+// password Secret to record once the differences are applied.
+func (e *external) diff(ctx context.Context, vm *proxmoxv1alpha1.VirtualMachine, spec proxmoxv1alpha1.VirtualMachineSpec, cfg map[string]interface{}) ([]configDiff, string, error) {
+	if meta.WasDeleted(vm) {
+		// The password Secret may be gone already; it no longer matters.
+		return diffConfig(spec, cfg), "", nil
 	}
+	password, version, err := e.cloudInitPassword(ctx, vm)
+	if err != nil {
+		return nil, "", err
+	}
+	return append(diffConfig(spec, cfg), passwordDiff(vm, cfg, password, version)...), version, nil
+}
 
-	e.log.Info("VM creation initiated successfully", "VMID", vm.Spec.VMID)
-	return managed.ExternalCreation{}, nil
+// describeError wraps a Proxmox error with a hint on whether it resolves on its
+// own (the VM is busy and the call will be retried) or needs a change to the
+// spec or the credentials. Failures needing a change are recorded with reject.
+func describeError(err error, action string) error {
+	switch {
+	case proxmoxclient.IsRetryable(err):
+		return errors.Wrapf(err, "cannot %s: Proxmox API is temporarily unavailable, will retry", action)
+	case proxmoxclient.IsLocked(err):
+		return errors.Wrapf(err, "cannot %s: VM is locked by another operation, will retry", action)
+	case proxmoxclient.IsUnauthorized(err):
+		return errors.Wrapf(err, "cannot %s: check the ProviderConfig credentials and their privileges", action)
+	case proxmoxclient.IsValidation(err):
+		return errors.Wrapf(err, "cannot %s: Proxmox rejected the VM configuration", action)
+	case proxmoxclient.IsConflict(err):
+		return errors.Wrapf(err, "cannot %s: conflicts with an existing Proxmox resource", action)
+	default:
+		return errors.Wrapf(err, "cannot %s", action)
+	}
 }
 
-// Helper function to convert boolean to "0" or "1" for Proxmox
-func boolToProxmoxString(val bool) string {
-	if val {
+// Helper function to convert boolean to "0" or "1" for Proxmox, or an empty
+// string when not set
+func boolToProxmoxString(val *bool) string {
+	if val == nil {
+		return ""
+	}
+	if *val {
 		return "1"
 	}
 	return "0"
@@ -183,19 +657,119 @@ func (e *external) Update(ctx context.Context, mg resource.Managed) (managed.Ext
 	if !ok {
 		return managed.ExternalUpdate{}, errors.New("managed resource is not a VirtualMachine")
 	}
+	ctx, cancel := e.withTimeout(ctx)
+	defer cancel()
 
-	e.log.Info("Preparing VM update payload", "VMID", vm.Spec.VMID)
-	payload := map[string]interface{}{
-		"name":    vm.Spec.Name,
-		"memory":  vm.Spec.Memory,
-		"cores":   vm.Spec.Cores,
-		"sockets": vm.Spec.Sockets,
+	node := e.vmNode(vm)
+	config, err := e.client.GetVMConfig(ctx, node, vm.Spec.VMID)
+	if err != nil {
+		return managed.ExternalUpdate{}, describeError(err, "read VM config")
+	}
+
+	spec := vm.Spec.DeepCopy()
+	if _, err := resolveIndexes(spec, config); err != nil {
+		setRejected(vm, err)
+		return managed.ExternalUpdate{}, errors.Wrap(err, "invalid VM spec")
+	}
+
+	resizes, err := diskResizes(*spec, config)
+	if err != nil {
+		setRejected(vm, err)
+		return managed.ExternalUpdate{}, errors.Wrap(err, "invalid VM spec")
 	}
-	err := e.client.Update(vm.Spec.VMID, payload)
+
+	moves := diskMoves(*spec, config)
+
+	diffs, passwordVersion, err := e.diff(ctx, vm, *spec, config)
 	if err != nil {
-		e.log.Error(err, "Failed to update VM", "VMID", vm.Spec.VMID)
+		return managed.ExternalUpdate{}, err
+	}
+	payload := updatePayload(config, diffs)
+	action := powerAction(vm.Status.AtProvider.PowerState, vm.Spec.PowerState)
+	if len(payload) == 0 && len(moves) == 0 && len(resizes) == 0 && action == "" && len(vm.Status.AtProvider.PendingChanges) == 0 {
+		e.log.Info("No config changes can be applied", "VMID", vm.Spec.VMID)
+		return managed.ExternalUpdate{}, nil
+	}
+
+	if len(payload) > 0 {
+		e.log.Info("Preparing VM update payload", "VMID", vm.Spec.VMID)
+		e.log.V(1).Info("VM update payload", "VMID", vm.Spec.VMID, "payload", redacted(payload))
+		upid, err := e.client.Update(ctx, node, vm.Spec.VMID, payload)
+		if err != nil {
+			e.log.Error(err, "Failed to update VM", "VMID", vm.Spec.VMID)
+			err = describeError(err, "update VM")
+			reject(vm, err)
+			return managed.ExternalUpdate{}, err
+		}
+		recordPasswordVersion(vm, passwordVersion)
+
+		// Proxmox rebuilds the cloud-init drive only when the VM starts; do it
+		// now so that the settings apply on the next boot of a running VM.
+		if upid == "" && changesCloudInit(payload) {
+			e.log.Info("Regenerating cloud-init drive", "VMID", vm.Spec.VMID)
+			if err := e.client.RegenerateCloudInit(ctx, node, vm.Spec.VMID); err != nil {
+				return managed.ExternalUpdate{}, describeError(err, "regenerate cloud-init drive")
+			}
+		}
+		if upid != "" {
+			// Resize once the update task has finished, on a later reconcile.
+			trackTask(vm, upid, taskOpUpdate)
+			return managed.ExternalUpdate{}, nil
+		}
+	}
+
+	// Disks move to another storage with their data, one task at a time; they
+	// are resized on a later reconcile, once on the target storage.
+	for _, m := range moves {
+		e.log.Info("Moving VM disk", "VMID", vm.Spec.VMID, "Disk", m.Disk, "Storage", m.Storage)
+		upid, err := e.client.MoveDisk(ctx, node, vm.Spec.VMID, m.Disk, m.Storage, m.Format)
+		if err != nil {
+			err = describeError(err, "move disk "+m.Disk)
+			reject(vm, err)
+			return managed.ExternalUpdate{}, err
+		}
+		if upid != "" {
+			trackTask(vm, upid, taskOpMove)
+			return managed.ExternalUpdate{}, nil
+		}
+	}
+
+	// Disks grow online; a resize running as a task is waited for before the next one.
+	for _, r := range resizes {
+		e.log.Info("Resizing VM disk", "VMID", vm.Spec.VMID, "Disk", r.Disk, "Size", r.Size)
+		upid, err := e.client.Resize(ctx, node, vm.Spec.VMID, r.Disk, r.Size)
+		if err != nil {
+			err = describeError(err, "resize disk "+r.Disk)
+			reject(vm, err)
+			return managed.ExternalUpdate{}, err
+		}
+		if upid != "" {
+			trackTask(vm, upid, taskOpResize)
+			return managed.ExternalUpdate{}, nil
+		}
+	}
+
+	// Changes Proxmox cannot hot-plug apply once the VM reboots, which the
+	// reboot policy may allow now.
+	if action == "" {
+		reboot, err := e.needsReboot(ctx, vm, node, len(payload) > 0)
+		if err != nil {
+			return managed.ExternalUpdate{}, describeError(err, "read pending VM changes")
+		}
+		if reboot {
+			e.log.Info("Rebooting VM to apply pending changes", "VMID", vm.Spec.VMID, "Changes", vm.Status.AtProvider.PendingChanges)
+			action = powerReboot
+		}
+	}
+
+	if action != "" {
+		upid, err := e.changePowerState(ctx, vm, node, action)
+		if err != nil {
+			return managed.ExternalUpdate{}, describeError(err, action+" VM")
+		}
+		trackTask(vm, upid, taskOpPower)
 	}
-	return managed.ExternalUpdate{}, errors.Wrap(err, "cannot update VM")
+	return managed.ExternalUpdate{}, nil
 }
 
 func (e *external) Delete(ctx context.Context, mg resource.Managed) (managed.ExternalDelete, error) {
@@ -206,39 +780,74 @@ func (e *external) Delete(ctx context.Context, mg resource.Managed) (managed.Ext
 	if !ok {
 		return managed.ExternalDelete{}, errors.New("managed resource is not a VirtualMachine")
 	}
+	ctx, cancel := e.withTimeout(ctx)
+	defer cancel()
 
 	// Set condition to indicate deletion is in progress
 	vm.SetConditions(xpv1.Deleting())
 
-	// Attempt to delete the VM from Proxmox
-	err := e.client.Delete(vm.Spec.VMID)
+	// The reconciler orphans VMs the policies do not allow deleting without
+	// calling Delete; guard against destroying one all the same.
+	if !managementPolicies(vm).ShouldDelete() {
+		e.log.Info("Orphaning VM as the policies do not allow deleting it", "VMID", vm.Spec.VMID)
+		return managed.ExternalDelete{}, nil
+	}
 
-	// If VM does not exist (IsNotFound error), or if deletion was successfully initiated, treat as deleted
-	if proxmoxclient.IsNotFound(err) || err == nil {
-		e.log.Info("VM deletion initiated or VM does not exist in Proxmox", "VMID", vm.Spec.VMID)
+	// The reconciler keeps calling Delete while the VM exists; wait for the task already started.
+	if pendingTask(vm) != "" {
+		return managed.ExternalDelete{}, nil
+	}
 
-		// Remove finalizer since the VM is no longer present in Proxmox
-		RemoveFinalizer(vm, finalizerName)
-		if updateErr := e.kube.Update(ctx, vm); updateErr != nil {
-			return managed.ExternalDelete{}, errors.Wrap(updateErr, "failed to remove finalizer after VM deletion")
+	// Proxmox only destroys stopped VMs. There is no point in waiting for the
+	// guest to shut down.
+	node := e.vmNode(vm)
+	if action := powerAction(vm.Status.AtProvider.PowerState, proxmoxv1alpha1.PowerStateStopped); action != "" {
+		if action == powerShutdown {
+			action = powerStop
 		}
-
+		upid, err := e.changePowerState(ctx, vm, node, action)
+		if err != nil {
+			return managed.ExternalDelete{}, describeError(err, action+" VM before deletion")
+		}
+		trackTask(vm, upid, taskOpPower)
 		return managed.ExternalDelete{}, nil
 	}
 
-	// If VM deletion was successful, remove finalizer and update Kubernetes resource
-	e.log.Info("VM deletion initiated successfully", "VMID", vm.Spec.VMID)
-	RemoveFinalizer(vm, finalizerName)
-	if updateErr := e.kube.Update(ctx, vm); updateErr != nil {
-		return managed.ExternalDelete{}, errors.Wrap(updateErr, "failed to remove finalizer after VM deletion")
+	// Attempt to delete the VM from Proxmox
+	upid, err := e.client.Delete(ctx, node, vm.Spec.VMID)
+	if proxmoxclient.IsNotFound(err) {
+		e.log.Info("VM does not exist in Proxmox", "VMID", vm.Spec.VMID)
+		return managed.ExternalDelete{}, nil
+	}
+	if err != nil {
+		return managed.ExternalDelete{}, describeError(err, "delete VM")
 	}
 
+	// The finalizer is removed by Observe once the deletion task has finished
+	// and the VM is gone from Proxmox.
+	trackTask(vm, upid, taskOpDelete)
+	e.log.Info("VM deletion initiated successfully", "VMID", vm.Spec.VMID, "UPID", upid)
 	return managed.ExternalDelete{}, nil
 }
 
 func (e *external) Disconnect(ctx context.Context) error {
 	e.log.Info("Disconnecting from Proxmox API")
-	return nil
+	return e.reportEndpoint(ctx)
+}
+
+// reportEndpoint records the endpoint in use in the ProviderConfig status, which
+// changes when the client failed over to another cluster member.
+func (e *external) reportEndpoint(ctx context.Context) error {
+	active := e.client.ActiveEndpoint()
+	if e.pc.Status.ActiveEndpoint == active {
+		return nil
+	}
+	if e.pc.Status.ActiveEndpoint != "" {
+		e.log.Info("Proxmox endpoint changed", "ProviderConfig", e.pc.Name, "From", e.pc.Status.ActiveEndpoint, "To", active)
+	}
+	patch := client.MergeFrom(e.pc.DeepCopy())
+	e.pc.Status.ActiveEndpoint = active
+	return errors.Wrap(e.kube.Status().Patch(ctx, e.pc, patch), "cannot record active Proxmox endpoint")
 }
 
 const finalizerName = "finalizer.crossplane.io"
@@ -0,0 +1,76 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+
+	proxmoxv1alpha1 "provider-proxmox/api/v1alpha1"
+	"provider-proxmox/internal/proxmoxclient"
+)
+
+func TestNewProxmoxClient(t *testing.T) {
+	password := map[string][]byte{"username": []byte("root@pam"), "password": []byte("secret")}
+	token := map[string][]byte{"tokenID": []byte("root@pam!ci"), "tokenSecret": []byte("s3cret")}
+	both := map[string][]byte{}
+	for k, v := range password {
+		both[k] = v
+	}
+	for k, v := range token {
+		both[k] = v
+	}
+
+	tests := []struct {
+		name      string
+		method    proxmoxv1alpha1.AuthMethod
+		data      map[string][]byte
+		wantToken string // APIToken of the client, empty for ticket authentication
+		wantErr   bool
+	}{
+		{name: "password keys", data: password},
+		{name: "token keys", data: token, wantToken: "root@pam!ci=s3cret"},
+		{name: "token preferred without method", data: both, wantToken: "root@pam!ci=s3cret"},
+		{name: "explicit password", method: proxmoxv1alpha1.AuthMethodPassword, data: both},
+		{name: "explicit token", method: proxmoxv1alpha1.AuthMethodAPIToken, data: both, wantToken: "root@pam!ci=s3cret"},
+		{name: "explicit token without keys", method: proxmoxv1alpha1.AuthMethodAPIToken, data: password, wantErr: true},
+		{name: "unsupported method", method: "Kerberos", data: both, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logins atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/api2/json/access/ticket" && r.FormValue("username") == "root@pam" && r.FormValue("password") == "secret" {
+					logins.Add(1)
+					_, _ = w.Write([]byte(`{"data":{"ticket":"PVE:root@pam:1234","CSRFPreventionToken":"1234:csrf"}}`))
+					return
+				}
+				w.WriteHeader(http.StatusUnauthorized)
+			}))
+			defer srv.Close()
+
+			pc := &proxmoxv1alpha1.ProviderConfig{Spec: proxmoxv1alpha1.ProviderConfigSpec{Endpoint: srv.URL, AuthMethod: tt.method}}
+			c, err := newProxmoxClient(context.Background(), pc, &corev1.Secret{Data: tt.data}, proxmoxclient.Options{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newProxmoxClient() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if c.APIToken != tt.wantToken {
+				t.Errorf("newProxmoxClient() APIToken = %q, want %q", c.APIToken, tt.wantToken)
+			}
+			wantLogins := int32(1)
+			if tt.wantToken != "" {
+				wantLogins = 0
+			}
+			if got := logins.Load(); got != wantLogins {
+				t.Errorf("newProxmoxClient() logged in %d times, want %d", got, wantLogins)
+			}
+		})
+	}
+}
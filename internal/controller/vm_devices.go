@@ -0,0 +1,523 @@
+package controller
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	proxmoxv1alpha1 "provider-proxmox/api/v1alpha1"
+	"provider-proxmox/internal/properties"
+)
+
+// maxNetworkDevices is the number of netN options Proxmox supports.
+const maxNetworkDevices = 32
+
+// diskBusSlots is the number of disks Proxmox supports on each bus.
+var diskBusSlots = map[proxmoxv1alpha1.DiskBus]int{
+	proxmoxv1alpha1.DiskBusSCSI:   31,
+	proxmoxv1alpha1.DiskBusVirtIO: 16,
+	proxmoxv1alpha1.DiskBusSATA:   6,
+	proxmoxv1alpha1.DiskBusIDE:    4,
+}
+
+var (
+	// networkKeyRe matches the config keys of network devices.
+	networkKeyRe = regexp.MustCompile(`^net(\d+)$`)
+	// diskKeyRe matches the config keys of disks attached to a bus.
+	diskKeyRe = regexp.MustCompile(`^(scsi|virtio|sata|ide)(\d+)$`)
+	// unusedKeyRe matches the config keys of detached disks.
+	unusedKeyRe = regexp.MustCompile(`^unused(\d+)$`)
+)
+
+// validateDevices checks the devices of the spec for conflicts the CRD schema
+// cannot express.
+func validateDevices(spec proxmoxv1alpha1.VirtualMachineSpec) error {
+	if len(spec.NetworkDevices) > maxNetworkDevices {
+		return errors.Errorf("at most %d network devices are supported", maxNetworkDevices)
+	}
+	nets := make([]*int, len(spec.NetworkDevices))
+	for i := range spec.NetworkDevices {
+		nets[i] = spec.NetworkDevices[i].Index
+	}
+	if err := validateIndexes(nets, maxNetworkDevices); err != nil {
+		return errors.Wrap(err, "network devices")
+	}
+
+	for bus, disks := range disksByBus(spec.Disks) {
+		slots, ok := diskBusSlots[bus]
+		if !ok {
+			return errors.Errorf("unsupported disk bus %q", bus)
+		}
+		if len(disks) > slots {
+			return errors.Errorf("at most %d disks are supported on bus %s", slots, bus)
+		}
+		indexes := make([]*int, len(disks))
+		for i, d := range disks {
+			indexes[i] = d.Index
+		}
+		if err := validateIndexes(indexes, slots); err != nil {
+			return errors.Wrapf(err, "%s disks", bus)
+		}
+	}
+	return validateCloudInit(spec)
+}
+
+// validateIndexes checks that explicit indexes are unique and below limit.
+func validateIndexes(indexes []*int, limit int) error {
+	used := map[int]bool{}
+	for _, index := range indexes {
+		if index == nil {
+			continue
+		}
+		if *index < 0 || *index >= limit {
+			return errors.Errorf("index %d is out of range 0-%d", *index, limit-1)
+		}
+		if used[*index] {
+			return errors.Errorf("index %d is used more than once", *index)
+		}
+		used[*index] = true
+	}
+	return nil
+}
+
+// assignIndexes returns the index of each device of a list. Devices without an
+// index take the lowest index not used by another device, in the order they
+// are listed. The index is -1 for devices left without one, which
+// validateDevices reports.
+func assignIndexes(indexes []*int, limit int) []int {
+	used := map[int]bool{}
+	for _, index := range indexes {
+		if index != nil {
+			used[*index] = true
+		}
+	}
+	assigned := make([]int, len(indexes))
+	next := 0
+	for i, index := range indexes {
+		if index != nil {
+			assigned[i] = *index
+			continue
+		}
+		for used[next] {
+			next++
+		}
+		if next >= limit {
+			assigned[i] = -1
+			continue
+		}
+		used[next] = true
+		assigned[i] = next
+	}
+	return assigned
+}
+
+// networkIndexes returns the netN index of each network device of the spec.
+func networkIndexes(devices []proxmoxv1alpha1.NetworkDevice) []int {
+	indexes := make([]*int, len(devices))
+	for i := range devices {
+		indexes[i] = devices[i].Index
+	}
+	return assignIndexes(indexes, maxNetworkDevices)
+}
+
+// diskIndexes returns the index of each disk of the spec on its bus.
+func diskIndexes(disks []proxmoxv1alpha1.Disk) []int {
+	assigned := make([]int, len(disks))
+	for bus, onBus := range disksByBus(disks) {
+		indexes := make([]*int, len(onBus))
+		for i, d := range onBus {
+			indexes[i] = d.Index
+		}
+		for i, index := range assignIndexes(indexes, diskBusSlots[bus]) {
+			assigned[onBus[i].position] = index
+		}
+	}
+	return assigned
+}
+
+// busDisk is a disk of the spec with its position in the disks list.
+type busDisk struct {
+	*proxmoxv1alpha1.Disk
+	position int
+}
+
+// disksByBus groups the disks of the spec by bus, in the order they are listed.
+func disksByBus(disks []proxmoxv1alpha1.Disk) map[proxmoxv1alpha1.DiskBus][]busDisk {
+	byBus := map[proxmoxv1alpha1.DiskBus][]busDisk{}
+	for i := range disks {
+		bus := diskBus(&disks[i])
+		byBus[bus] = append(byBus[bus], busDisk{Disk: &disks[i], position: i})
+	}
+	return byBus
+}
+
+// diskBus returns the bus of a disk, which the API server defaults to scsi.
+func diskBus(d *proxmoxv1alpha1.Disk) proxmoxv1alpha1.DiskBus {
+	if d.Bus == "" {
+		return proxmoxv1alpha1.DiskBusSCSI
+	}
+	return d.Bus
+}
+
+// resolveIndexes sets the index of the network devices and disks of the spec
+// that do not set one, against the devices of the VM config, nil for a VM not
+// created yet. A device the spec identifies, by the MAC address of a network
+// device or the volume of a disk, takes the index it has on the VM. Other
+// devices take the lowest index free in the spec and on the VM, so that they
+// never take over a device of the VM. Which device of the VM such a device
+// stands for is unknown while the VM has devices the spec does not index, and
+// an error is returned rather than renumbering them. It reports whether an
+// index was set.
+func resolveIndexes(spec *proxmoxv1alpha1.VirtualMachineSpec, cfg map[string]interface{}) (bool, error) {
+	changed := false
+
+	nets := make([]*int, len(spec.NetworkDevices))
+	macs := make([]string, len(spec.NetworkDevices))
+	for i := range spec.NetworkDevices {
+		nets[i], macs[i] = spec.NetworkDevices[i].Index, spec.NetworkDevices[i].MACAddress
+	}
+	observed := map[int]string{}
+	for _, key := range matchingKeys(cfg, networkKeyRe) {
+		index, _ := strconv.Atoi(networkKeyRe.FindStringSubmatch(key)[1])
+		observed[index] = ""
+		if dev, err := properties.ParseNetworkDevice(configString(cfg, key)); err == nil {
+			observed[index] = dev.MAC
+		}
+	}
+	nets, err := resolveBusIndexes("net", nets, macs, observed, maxNetworkDevices, properties.SameMAC)
+	if err != nil {
+		return false, errors.Wrap(err, "network devices")
+	}
+	for i, index := range nets {
+		if spec.NetworkDevices[i].Index == nil && index != nil {
+			spec.NetworkDevices[i].Index = index
+			changed = true
+		}
+	}
+
+	for bus, disks := range disksByBus(spec.Disks) {
+		indexes := make([]*int, len(disks))
+		volumes := make([]string, len(disks))
+		for i, d := range disks {
+			indexes[i], volumes[i] = d.Index, d.Volume
+		}
+		observed := map[int]string{}
+		for _, key := range matchingKeys(cfg, diskKeyRe) {
+			m := diskKeyRe.FindStringSubmatch(key)
+			option := configString(cfg, key)
+			if m[1] != string(bus) || isCloudInitDrive(option) {
+				continue
+			}
+			index, _ := strconv.Atoi(m[2])
+			observed[index] = ""
+			if d, err := properties.ParseDisk(option); err == nil {
+				observed[index] = d.File
+			}
+		}
+		indexes, err := resolveBusIndexes(string(bus), indexes, volumes, observed, diskBusSlots[bus], func(a, b string) bool { return a == b })
+		if err != nil {
+			return false, errors.Wrapf(err, "%s disks", bus)
+		}
+		for i, index := range indexes {
+			if disks[i].Index == nil && index != nil {
+				disks[i].Index = index
+				changed = true
+			}
+		}
+	}
+	return changed, nil
+}
+
+// resolveBusIndexes resolves the indexes of the devices of one bus, whose
+// config keys start with prefix. ids are the identities the spec gives the
+// devices, if any, and observed maps the indexes used on the VM to the
+// identity of their device. Devices left without an index because the bus is
+// full are reported by validateDevices.
+func resolveBusIndexes(prefix string, indexes []*int, ids []string, observed map[int]string, limit int, same func(a, b string) bool) ([]*int, error) {
+	resolved := append([]*int(nil), indexes...)
+	used := map[int]bool{}
+	for _, index := range resolved {
+		if index != nil {
+			used[*index] = true
+		}
+	}
+	observedIndexes := make([]int, 0, len(observed))
+	for index := range observed {
+		observedIndexes = append(observedIndexes, index)
+	}
+	sort.Ints(observedIndexes)
+
+	for i, index := range resolved {
+		if index != nil || ids[i] == "" {
+			continue
+		}
+		for _, o := range observedIndexes {
+			if !used[o] && observed[o] != "" && same(ids[i], observed[o]) {
+				o := o
+				resolved[i], used[o] = &o, true
+				break
+			}
+		}
+	}
+
+	var unmatched []string
+	for _, o := range observedIndexes {
+		if !used[o] {
+			unmatched = append(unmatched, prefix+strconv.Itoa(o))
+		}
+	}
+	next := 0
+	for i, index := range resolved {
+		if index != nil {
+			continue
+		}
+		if len(unmatched) > 0 {
+			return nil, errors.Errorf("cannot tell which of %s a device without an index stands for; set the index of each device",
+				strings.Join(unmatched, ", "))
+		}
+		for {
+			if _, ok := observed[next]; !ok && !used[next] {
+				break
+			}
+			next++
+		}
+		if next >= limit {
+			continue
+		}
+		n := next
+		resolved[i], used[n] = &n, true
+	}
+	return resolved, nil
+}
+
+// networkOptions returns the desired netN options of the spec by config key.
+// Devices without a MAC address reuse the one recorded in macs for their
+// index, so that a VM created again keeps its addresses.
+func networkOptions(devices []proxmoxv1alpha1.NetworkDevice, macs map[int]string) map[string]string {
+	options := make(map[string]string, len(devices))
+	for i, index := range networkIndexes(devices) {
+		if index < 0 {
+			continue
+		}
+		dev := networkDevice(&devices[i])
+		if dev.MAC == "" {
+			dev.MAC = macs[index]
+		}
+		options[networkKey(index)] = dev.String()
+	}
+	return options
+}
+
+// diskOptions returns the desired disk options of the spec by config key.
+func diskOptions(disks []proxmoxv1alpha1.Disk) map[string]string {
+	options := make(map[string]string, len(disks))
+	for i, index := range diskIndexes(disks) {
+		if index < 0 {
+			continue
+		}
+		options[string(diskBus(&disks[i]))+strconv.Itoa(index)] = diskDevice(&disks[i]).String()
+	}
+	return options
+}
+
+// diskResize is a disk to grow to the size set in the spec.
+type diskResize struct {
+	Disk string // Config key of the disk, e.g. scsi0
+	Size string // Absolute size, e.g. 64G
+}
+
+// diskMove is a disk to move to the storage set in the spec.
+type diskMove struct {
+	Disk    string // Config key of the disk, e.g. scsi0
+	Storage string // Target storage, e.g. ceph
+	Format  string // Target format, empty to let Proxmox choose
+}
+
+// diskMoves compares the storage of the disks the spec allocates with the VM
+// config. It returns the disks attached on another storage, which are moved
+// with their data rather than allocated again.
+func diskMoves(spec proxmoxv1alpha1.VirtualMachineSpec, cfg map[string]interface{}) []diskMove {
+	options := diskOptions(spec.Disks)
+	var moves []diskMove
+	for _, key := range sortedKeys(options) {
+		want, err := properties.ParseDisk(options[key])
+		if err != nil {
+			continue
+		}
+		storage, _, ok := want.Allocation()
+		if !ok {
+			continue
+		}
+		have, err := properties.ParseDisk(configString(cfg, key))
+		if err != nil || have.Storage() == "" || have.Storage() == storage {
+			continue
+		}
+		moves = append(moves, diskMove{Disk: key, Storage: storage, Format: want.Format})
+	}
+	return moves
+}
+
+// diskResizes compares the size of the disks the spec allocates with the VM
+// config. It returns the disks to grow, and an error for a disk the spec
+// would shrink, which Proxmox does not support.
+func diskResizes(spec proxmoxv1alpha1.VirtualMachineSpec, cfg map[string]interface{}) ([]diskResize, error) {
+	options := diskOptions(spec.Disks)
+	var resizes []diskResize
+	for _, key := range sortedKeys(options) {
+		want, err := properties.ParseDisk(options[key])
+		if err != nil {
+			continue
+		}
+		storage, size, ok := want.Allocation()
+		if !ok {
+			continue
+		}
+		have, err := properties.ParseDisk(configString(cfg, key))
+		if err != nil || have.Storage() != storage || have.Size == "" {
+			// A missing disk is allocated; one on another storage is moved
+			// first and resized once it is there.
+			continue
+		}
+		wantMiB, haveMiB := properties.SizeMiB(size), properties.SizeMiB(have.Size)
+		switch {
+		case wantMiB < haveMiB:
+			return nil, errors.Errorf("disk %s cannot shrink from %s to %s; Proxmox only grows disks", key, have.Size, formatSize(wantMiB))
+		case wantMiB > haveMiB:
+			resizes = append(resizes, diskResize{Disk: key, Size: formatSize(wantMiB)})
+		}
+	}
+	return resizes, nil
+}
+
+// formatSize renders a size in MiB as Proxmox prints it, e.g. "32G" or "512M".
+func formatSize(mib int64) string {
+	if mib%1024 == 0 {
+		return strconv.FormatInt(mib/1024, 10) + "G"
+	}
+	return strconv.FormatInt(mib, 10) + "M"
+}
+
+// observeNetworks returns the network devices of a VM config, ordered by index.
+func observeNetworks(cfg map[string]interface{}) []proxmoxv1alpha1.NetworkDeviceObservation {
+	var observed []proxmoxv1alpha1.NetworkDeviceObservation
+	for _, key := range matchingKeys(cfg, networkKeyRe) {
+		index, _ := strconv.Atoi(networkKeyRe.FindStringSubmatch(key)[1])
+		dev, err := properties.ParseNetworkDevice(configString(cfg, key))
+		if err != nil {
+			continue
+		}
+		observed = append(observed, proxmoxv1alpha1.NetworkDeviceObservation{
+			Index:      index,
+			Model:      dev.Model,
+			MACAddress: dev.MAC,
+			Bridge:     dev.Bridge,
+		})
+	}
+	return observed
+}
+
+// observeDisks returns the attached and unused disks of a VM config.
+func observeDisks(cfg map[string]interface{}) []proxmoxv1alpha1.DiskObservation {
+	var observed []proxmoxv1alpha1.DiskObservation
+	for _, key := range append(matchingKeys(cfg, diskKeyRe), matchingKeys(cfg, unusedKeyRe)...) {
+		disk, err := properties.ParseDisk(configString(cfg, key))
+		if err != nil {
+			continue
+		}
+		observed = append(observed, proxmoxv1alpha1.DiskObservation{Name: key, Volume: disk.File, Size: disk.Size})
+	}
+	return observed
+}
+
+// observedMACs returns the MAC addresses recorded in the status by index.
+func observedMACs(observed []proxmoxv1alpha1.NetworkDeviceObservation) map[int]string {
+	macs := make(map[int]string, len(observed))
+	for _, n := range observed {
+		macs[n.Index] = n.MACAddress
+	}
+	return macs
+}
+
+// matchingKeys returns the keys of a VM config matching a device pattern,
+// ordered by bus and index.
+func matchingKeys(cfg map[string]interface{}, re *regexp.Regexp) []string {
+	var keys []string
+	for key := range cfg {
+		if re.MatchString(key) {
+			keys = append(keys, key)
+		}
+	}
+	sortDeviceKeys(keys)
+	return keys
+}
+
+// sortDeviceKeys orders device keys such as "scsi10" by bus, then by index.
+func sortDeviceKeys(keys []string) {
+	sort.Slice(keys, func(i, j int) bool {
+		if len(keys[i]) != len(keys[j]) && deviceBus(keys[i]) == deviceBus(keys[j]) {
+			return len(keys[i]) < len(keys[j])
+		}
+		return keys[i] < keys[j]
+	})
+}
+
+// deviceBus returns a device key without its index, e.g. "scsi" for "scsi10".
+func deviceBus(key string) string {
+	end := len(key)
+	for end > 0 && key[end-1] >= '0' && key[end-1] <= '9' {
+		end--
+	}
+	return key[:end]
+}
+
+func networkKey(index int) string {
+	return "net" + strconv.Itoa(index)
+}
+
+// networkDevice converts a network device of the spec to its Proxmox option.
+func networkDevice(n *proxmoxv1alpha1.NetworkDevice) *properties.NetworkDevice {
+	dev := &properties.NetworkDevice{
+		Model:    n.Model,
+		MAC:      n.MACAddress,
+		Bridge:   n.Bridge,
+		Firewall: n.Firewall,
+		LinkDown: n.LinkDown,
+		Rate:     n.Rate,
+		MTU:      n.MTU,
+		Queues:   n.Queues,
+	}
+	if dev.Model == "" {
+		dev.Model = "virtio"
+	}
+	if n.VLANTag != nil {
+		dev.Tag = *n.VLANTag
+	}
+	return dev
+}
+
+// diskDevice converts a disk of the spec to its Proxmox option. Disks without
+// a volume ask Proxmox to allocate one, e.g. "local-lvm:32".
+func diskDevice(d *proxmoxv1alpha1.Disk) *properties.Disk {
+	dev := &properties.Disk{
+		File:     d.Volume,
+		Media:    d.Media,
+		Format:   d.Format,
+		Cache:    d.Cache,
+		IOThread: d.IOThread,
+		Discard:  d.Discard,
+		SSD:      d.SSD,
+		Backup:   d.Backup,
+	}
+	if dev.File == "" {
+		dev.File = d.Storage + ":" + sizeGiB(d.Size)
+	}
+	return dev
+}
+
+// sizeGiB converts a size such as "32G" or "512M" to GiB, as used in allocations.
+func sizeGiB(size string) string {
+	return strconv.FormatFloat(float64(properties.SizeMiB(size))/1024, 'f', -1, 64)
+}
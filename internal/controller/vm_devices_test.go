@@ -0,0 +1,310 @@
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	proxmoxv1alpha1 "provider-proxmox/api/v1alpha1"
+)
+
+func TestNetworkIndexes(t *testing.T) {
+	index := func(i int) *int { return &i }
+	tests := []struct {
+		name    string
+		devices []proxmoxv1alpha1.NetworkDevice
+		want    []int
+	}{
+		{name: "in list order", devices: []proxmoxv1alpha1.NetworkDevice{{}, {}, {}}, want: []int{0, 1, 2}},
+		{name: "explicit indexes are skipped", devices: []proxmoxv1alpha1.NetworkDevice{{}, {Index: index(0)}, {}, {Index: index(2)}}, want: []int{1, 0, 3, 2}},
+		{name: "gaps are kept", devices: []proxmoxv1alpha1.NetworkDevice{{Index: index(4)}, {Index: index(1)}}, want: []int{4, 1}},
+		{name: "no free index", devices: append(make([]proxmoxv1alpha1.NetworkDevice, maxNetworkDevices), proxmoxv1alpha1.NetworkDevice{})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := networkIndexes(tt.devices)
+			if tt.want == nil {
+				if got[len(got)-1] != -1 {
+					t.Errorf("networkIndexes() assigned index %d beyond the limit", got[len(got)-1])
+				}
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("networkIndexes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiskIndexes(t *testing.T) {
+	index := func(i int) *int { return &i }
+	disks := []proxmoxv1alpha1.Disk{
+		{Storage: "local-lvm", Size: "32G"},
+		{Bus: proxmoxv1alpha1.DiskBusIDE, Index: index(2), Volume: "none", Media: "cdrom"},
+		{Bus: proxmoxv1alpha1.DiskBusVirtIO, Storage: "local-lvm", Size: "8G"},
+		{Bus: proxmoxv1alpha1.DiskBusSCSI, Index: index(0), Storage: "local-lvm", Size: "16G"},
+		{Bus: proxmoxv1alpha1.DiskBusIDE, Storage: "local-lvm", Size: "1G"},
+	}
+	if got, want := diskIndexes(disks), []int{1, 2, 0, 0, 0}; !reflect.DeepEqual(got, want) {
+		t.Errorf("diskIndexes() = %v, want %v", got, want)
+	}
+
+	got := diskOptions(disks)
+	want := map[string]string{
+		"scsi0":   "local-lvm:16",
+		"scsi1":   "local-lvm:32",
+		"virtio0": "local-lvm:8",
+		"ide0":    "local-lvm:1",
+		"ide2":    "none,media=cdrom",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("diskOptions() = %v, want %v", got, want)
+	}
+}
+
+func TestValidateDevices(t *testing.T) {
+	index := func(i int) *int { return &i }
+	sata := func(n int) []proxmoxv1alpha1.Disk {
+		disks := make([]proxmoxv1alpha1.Disk, n)
+		for i := range disks {
+			disks[i].Bus = proxmoxv1alpha1.DiskBusSATA
+		}
+		return disks
+	}
+	tests := []struct {
+		name    string
+		spec    proxmoxv1alpha1.VirtualMachineSpec
+		wantErr bool
+	}{
+		{name: "valid", spec: proxmoxv1alpha1.VirtualMachineSpec{
+			NetworkDevices: []proxmoxv1alpha1.NetworkDevice{{Index: index(1)}, {}},
+			Disks:          []proxmoxv1alpha1.Disk{{Index: index(30)}, {Bus: proxmoxv1alpha1.DiskBusSATA, Index: index(5)}},
+		}},
+		{name: "duplicate network index", spec: proxmoxv1alpha1.VirtualMachineSpec{
+			NetworkDevices: []proxmoxv1alpha1.NetworkDevice{{Index: index(1)}, {Index: index(1)}},
+		}, wantErr: true},
+		{name: "same index on other buses", spec: proxmoxv1alpha1.VirtualMachineSpec{
+			Disks: []proxmoxv1alpha1.Disk{{Index: index(0)}, {Bus: proxmoxv1alpha1.DiskBusVirtIO, Index: index(0)}},
+		}},
+		{name: "duplicate disk index", spec: proxmoxv1alpha1.VirtualMachineSpec{
+			Disks: []proxmoxv1alpha1.Disk{{Bus: proxmoxv1alpha1.DiskBusSATA, Index: index(0)}, {Bus: proxmoxv1alpha1.DiskBusSATA, Index: index(0)}},
+		}, wantErr: true},
+		{name: "beyond ide slots", spec: proxmoxv1alpha1.VirtualMachineSpec{
+			Disks: []proxmoxv1alpha1.Disk{{Bus: proxmoxv1alpha1.DiskBusIDE, Index: index(4)}},
+		}, wantErr: true},
+		{name: "too many sata disks", spec: proxmoxv1alpha1.VirtualMachineSpec{Disks: sata(7)}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateDevices(tt.spec); (err != nil) != tt.wantErr {
+				t.Errorf("validateDevices() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestResolveIndexes(t *testing.T) {
+	index := func(i int) *int { return &i }
+	cfg := map[string]interface{}{
+		"net0":  "virtio=BC:24:11:2E:4F:01,bridge=vmbr0",
+		"net1":  "virtio=BC:24:11:2E:4F:02,bridge=vmbr1",
+		"scsi0": "local-lvm:vm-101-disk-0,size=32G",
+		"scsi1": "local-lvm:vm-101-disk-1,size=64G",
+		"ide2":  "local-lvm:vm-101-cloudinit,media=cdrom",
+	}
+	tests := []struct {
+		name      string
+		spec      proxmoxv1alpha1.VirtualMachineSpec
+		cfg       map[string]interface{}
+		wantNets  []int
+		wantDisks []int
+		wantErr   bool
+	}{
+		{
+			name: "new VM in list order",
+			spec: proxmoxv1alpha1.VirtualMachineSpec{
+				NetworkDevices: []proxmoxv1alpha1.NetworkDevice{{}, {}},
+				Disks:          []proxmoxv1alpha1.Disk{{Storage: "local-lvm", Size: "32G"}, {Storage: "local-lvm", Size: "64G"}, {Bus: proxmoxv1alpha1.DiskBusIDE, Volume: "local:iso/debian.iso"}},
+			},
+			wantNets:  []int{0, 1},
+			wantDisks: []int{0, 1, 0},
+		},
+		{
+			name: "matched by MAC address and volume",
+			spec: proxmoxv1alpha1.VirtualMachineSpec{
+				NetworkDevices: []proxmoxv1alpha1.NetworkDevice{{MACAddress: "bc:24:11:2e:4f:02"}},
+				Disks:          []proxmoxv1alpha1.Disk{{Volume: "local-lvm:vm-101-disk-1"}},
+			},
+			cfg:       cfg,
+			wantNets:  []int{1},
+			wantDisks: []int{1},
+		},
+		{
+			name: "added devices skip those of the VM",
+			spec: proxmoxv1alpha1.VirtualMachineSpec{
+				NetworkDevices: []proxmoxv1alpha1.NetworkDevice{{}, {Index: index(1)}, {Index: index(0)}},
+				Disks:          []proxmoxv1alpha1.Disk{{Storage: "local-lvm", Size: "16G"}, {Index: index(1), Storage: "local-lvm", Size: "64G"}, {Index: index(0), Storage: "local-lvm", Size: "32G"}},
+			},
+			cfg:       cfg,
+			wantNets:  []int{2, 1, 0},
+			wantDisks: []int{2, 1, 0},
+		},
+		{
+			name: "removed devices are not renumbered",
+			spec: proxmoxv1alpha1.VirtualMachineSpec{
+				NetworkDevices: []proxmoxv1alpha1.NetworkDevice{{Index: index(1)}},
+				Disks:          []proxmoxv1alpha1.Disk{{Index: index(1), Storage: "local-lvm", Size: "64G"}},
+			},
+			cfg:       cfg,
+			wantNets:  []int{1},
+			wantDisks: []int{1},
+		},
+		{
+			name: "unindexed disk of an existing VM",
+			spec: proxmoxv1alpha1.VirtualMachineSpec{
+				Disks: []proxmoxv1alpha1.Disk{{Storage: "local-lvm", Size: "64G"}},
+			},
+			cfg:     cfg,
+			wantErr: true,
+		},
+		{
+			name: "unindexed network device of an existing VM",
+			spec: proxmoxv1alpha1.VirtualMachineSpec{
+				NetworkDevices: []proxmoxv1alpha1.NetworkDevice{{Index: index(0)}, {Bridge: "vmbr1"}},
+			},
+			cfg:     cfg,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := tt.spec.DeepCopy()
+			_, err := resolveIndexes(spec, tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveIndexes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			var nets, disks []int
+			for _, d := range spec.NetworkDevices {
+				nets = append(nets, *d.Index)
+			}
+			for _, d := range spec.Disks {
+				disks = append(disks, *d.Index)
+			}
+			if !reflect.DeepEqual(nets, tt.wantNets) || !reflect.DeepEqual(disks, tt.wantDisks) {
+				t.Errorf("resolveIndexes() = %v, %v, want %v, %v", nets, disks, tt.wantNets, tt.wantDisks)
+			}
+		})
+	}
+}
+
+func TestNetworkOptions(t *testing.T) {
+	spec := proxmoxv1alpha1.VirtualMachineSpec{NetworkDevices: []proxmoxv1alpha1.NetworkDevice{
+		{Bridge: "vmbr0"},
+		{Bridge: "vmbr1", MACAddress: "BC:24:11:2E:4F:09"},
+	}}
+	got := networkOptions(spec.NetworkDevices, map[int]string{0: "BC:24:11:2E:4F:01", 1: "BC:24:11:2E:4F:02"})
+	want := map[string]string{
+		"net0": "virtio=BC:24:11:2E:4F:01,bridge=vmbr0",
+		"net1": "virtio=BC:24:11:2E:4F:09,bridge=vmbr1",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("networkOptions() = %v, want %v", got, want)
+	}
+
+	if changed, _ := resolveIndexes(&spec, nil); !changed || *spec.NetworkDevices[0].Index != 0 || *spec.NetworkDevices[1].Index != 1 {
+		t.Errorf("resolveIndexes() did not record the assigned indexes")
+	}
+	if changed, _ := resolveIndexes(&spec, nil); changed {
+		t.Errorf("resolveIndexes() = true for indexes already set")
+	}
+}
+
+func TestDiskResizes(t *testing.T) {
+	spec := proxmoxv1alpha1.VirtualMachineSpec{Disks: []proxmoxv1alpha1.Disk{
+		{Storage: "local-lvm", Size: "64G"},
+		{Storage: "local-lvm", Size: "32G"},
+		{Storage: "local-lvm", Size: "1536M"},
+		{Storage: "ceph", Size: "64G"},
+		{Volume: "local-lvm:vm-101-disk-9"},
+	}}
+	cfg := map[string]interface{}{
+		"scsi0": "local-lvm:vm-101-disk-0,size=32G",
+		"scsi1": "local-lvm:vm-101-disk-1,size=32G",
+		"scsi2": "local-lvm:vm-101-disk-2,size=1G",
+		"scsi3": "local-lvm:vm-101-disk-3,size=32G",
+		"scsi4": "local-lvm:vm-101-disk-9,size=8G",
+	}
+	resizes, err := diskResizes(spec, cfg)
+	if err != nil {
+		t.Fatalf("diskResizes() error = %v", err)
+	}
+	want := []diskResize{{Disk: "scsi0", Size: "64G"}, {Disk: "scsi2", Size: "1536M"}}
+	if !reflect.DeepEqual(resizes, want) {
+		t.Errorf("diskResizes() = %v, want %v", resizes, want)
+	}
+
+	spec.Disks[1].Size = "16G"
+	if _, err := diskResizes(spec, cfg); err == nil {
+		t.Errorf("diskResizes() error = nil for a disk that would shrink")
+	}
+}
+
+func TestDiskMoves(t *testing.T) {
+	enabled := true
+	tests := []struct {
+		name        string
+		disk        proxmoxv1alpha1.Disk
+		observed    string
+		wantMoves   []diskMove
+		wantPayload map[string]interface{}
+	}{
+		{
+			name:      "storage change only",
+			disk:      proxmoxv1alpha1.Disk{Storage: "ceph", Size: "32G"},
+			observed:  "local-lvm:vm-101-disk-0,size=32G",
+			wantMoves: []diskMove{{Disk: "scsi0", Storage: "ceph"}},
+		},
+		{
+			name:        "storage and options change",
+			disk:        proxmoxv1alpha1.Disk{Storage: "ceph", Size: "32G", Format: "raw", IOThread: &enabled},
+			observed:    "local-lvm:vm-101-disk-0,size=32G",
+			wantMoves:   []diskMove{{Disk: "scsi0", Storage: "ceph", Format: "raw"}},
+			wantPayload: map[string]interface{}{"scsi0": "local-lvm:vm-101-disk-0,iothread=1,size=32G"},
+		},
+		{
+			name:     "same storage",
+			disk:     proxmoxv1alpha1.Disk{Storage: "local-lvm", Size: "32G"},
+			observed: "local-lvm:vm-101-disk-0,size=32G",
+		},
+		{
+			name:        "missing disk allocated",
+			disk:        proxmoxv1alpha1.Disk{Storage: "ceph", Size: "32G"},
+			wantPayload: map[string]interface{}{"scsi0": "ceph:32"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := proxmoxv1alpha1.VirtualMachineSpec{Disks: []proxmoxv1alpha1.Disk{tt.disk}}
+			cfg := map[string]interface{}{}
+			if tt.observed != "" {
+				cfg["scsi0"] = tt.observed
+			}
+			if got := diskMoves(spec, cfg); !reflect.DeepEqual(got, tt.wantMoves) {
+				t.Errorf("diskMoves() = %v, want %v", got, tt.wantMoves)
+			}
+			payload := updatePayload(cfg, diffConfig(spec, cfg))
+			if tt.wantPayload == nil {
+				tt.wantPayload = map[string]interface{}{}
+			}
+			if !reflect.DeepEqual(payload, tt.wantPayload) {
+				t.Errorf("updatePayload() = %v, want %v", payload, tt.wantPayload)
+			}
+		})
+	}
+}
@@ -0,0 +1,303 @@
+package controller
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	proxmoxv1alpha1 "provider-proxmox/api/v1alpha1"
+	"provider-proxmox/internal/properties"
+	"provider-proxmox/internal/proxmoxclient"
+)
+
+// configDiff describes a single spec field that differs from the live Proxmox config.
+type configDiff struct {
+	Key      string
+	Desired  string
+	Observed string
+}
+
+func (d configDiff) String() string {
+	if sensitiveKeys[d.Key] {
+		return fmt.Sprintf("%s: changed", d.Key)
+	}
+	if d.Desired == "" {
+		return fmt.Sprintf("%s: remove %q", d.Key, d.Observed)
+	}
+	return fmt.Sprintf("%s: want %q, got %q", d.Key, d.Desired, d.Observed)
+}
+
+// formatDiffs joins the differences into a single human readable message.
+func formatDiffs(diffs []configDiff) string {
+	parts := make([]string, 0, len(diffs))
+	for _, d := range diffs {
+		parts = append(parts, d.String())
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Defaults applied by Proxmox when a key is absent from the VM config.
+var configDefaults = map[string]string{
+	"cores":   "1",
+	"sockets": "1",
+	"numa":    "0",
+	"scsihw":  "lsi",
+}
+
+// diffConfig compares every VirtualMachineSpec field against the live config returned by Proxmox.
+func diffConfig(spec proxmoxv1alpha1.VirtualMachineSpec, cfg map[string]interface{}) []configDiff {
+	var diffs []configDiff
+
+	scalar := func(key, desired string) {
+		if desired == "" {
+			return
+		}
+		observed := configString(cfg, key)
+		if observed == "" {
+			observed = configDefaults[key]
+		}
+		if desired != observed {
+			diffs = append(diffs, configDiff{Key: key, Desired: desired, Observed: observed})
+		}
+	}
+
+	scalar("name", spec.Name)
+	scalar("memory", intString(spec.Memory))
+	scalar("cores", intString(spec.Cores))
+	scalar("cpu", spec.CPU)
+	scalar("sockets", intString(spec.Sockets))
+	scalar("numa", boolToProxmoxString(spec.Numa))
+	scalar("ostype", spec.OSType)
+	scalar("scsihw", spec.ScsiHW)
+	diffs = append(diffs, diffAgent(spec, cfg)...)
+
+	devices := func(desired map[string]string, observed []string, matches func(desired, observed string) bool) {
+		for _, key := range sortedKeys(desired) {
+			if !matches(desired[key], configString(cfg, key)) {
+				diffs = append(diffs, configDiff{Key: key, Desired: desired[key], Observed: configString(cfg, key)})
+			}
+		}
+		// An empty list leaves the devices of the VM alone, e.g. those of a template.
+		if len(desired) == 0 {
+			return
+		}
+		for _, key := range observed {
+			if _, ok := desired[key]; !ok {
+				diffs = append(diffs, configDiff{Key: key, Observed: configString(cfg, key)})
+			}
+		}
+	}
+	devices(networkOptions(spec.NetworkDevices, nil), matchingKeys(cfg, networkKeyRe), netMatches)
+	disks := diskOptions(spec.Disks)
+	var observedDisks []string
+	for _, key := range matchingKeys(cfg, diskKeyRe) {
+		// The cloud-init drive is managed with the cloud-init settings.
+		if !isCloudInitDrive(configString(cfg, key)) {
+			observedDisks = append(observedDisks, key)
+		}
+	}
+	devices(disks, observedDisks, diskMatches)
+
+	// Unused disks are destroyed only when asked to, and never when the spec
+	// attaches their volume again.
+	if spec.DetachedDisks == proxmoxv1alpha1.DetachedDiskDestroy {
+		attached := map[string]bool{}
+		for _, option := range disks {
+			if d, err := properties.ParseDisk(option); err == nil {
+				attached[d.File] = true
+			}
+		}
+		for _, key := range matchingKeys(cfg, unusedKeyRe) {
+			if d, err := properties.ParseDisk(configString(cfg, key)); err == nil && !attached[d.File] {
+				diffs = append(diffs, configDiff{Key: key, Observed: configString(cfg, key)})
+			}
+		}
+	}
+
+	return append(diffs, diffCloudInit(spec, cfg)...)
+}
+
+// updatePayload builds the config update needed to resolve the given differences.
+// Device keys keep the identity of the existing device (MAC address, backing
+// volume) so that an update only changes the options set in the spec. Devices
+// missing from the spec are deleted, which Proxmox turns into an unusedN disk
+// for disks with a volume, and unused disks are destroyed.
+func updatePayload(cfg map[string]interface{}, diffs []configDiff) map[string]interface{} {
+	payload := map[string]interface{}{}
+	for _, d := range diffs {
+		switch {
+		case d.Desired == "":
+			payload[d.Key] = proxmoxclient.Unset
+		case networkKeyRe.MatchString(d.Key):
+			payload[d.Key] = keepNetIdentity(d.Desired, configString(cfg, d.Key))
+		case diskKeyRe.MatchString(d.Key):
+			if v, ok := keepDiskVolume(d.Desired, configString(cfg, d.Key)); ok {
+				payload[d.Key] = v
+			}
+		default:
+			payload[d.Key] = d.Desired
+		}
+	}
+	return payload
+}
+
+// netMatches reports whether an observed network device satisfies the desired one.
+// The desired device may omit the MAC address, which Proxmox generates.
+func netMatches(desired, observed string) bool {
+	want, wantErr := properties.ParseNetworkDevice(desired)
+	have, haveErr := properties.ParseNetworkDevice(observed)
+	if wantErr != nil || haveErr != nil {
+		return desired == observed
+	}
+	if want.Model != have.Model {
+		return false
+	}
+	if want.MAC != "" && !properties.SameMAC(want.MAC, have.MAC) {
+		return false
+	}
+	return optionsMatch(want.Options()[1:], have.Options()[1:], netOptionKeys, nil)
+}
+
+// diskMatches reports whether an observed disk satisfies the desired one. A desired
+// allocation such as "local-lvm:32" matches any volume on that storage of that size.
+func diskMatches(desired, observed string) bool {
+	want, wantErr := properties.ParseDisk(desired)
+	have, haveErr := properties.ParseDisk(observed)
+	if wantErr != nil || haveErr != nil {
+		return desired == observed
+	}
+	if storage, size, ok := want.Allocation(); ok {
+		if have.Storage() != storage || properties.SizeMiB(size) != properties.SizeMiB(have.Size) {
+			return false
+		}
+	} else if want.File != have.File {
+		return false
+	}
+	return optionsMatch(want.Options()[1:], have.Options()[1:], diskOptionKeys, allocatedOptions)
+}
+
+// netOptionKeys and diskOptionKeys are the device options the spec models. An
+// option the spec leaves unset is expected at its default on the VM, so that
+// removing it from the spec is applied as well.
+var (
+	netOptionKeys  = []string{"bridge", "tag", "firewall", "link_down", "rate", "mtu", "queues"}
+	diskOptionKeys = []string{"media", "iothread", "ssd", "backup", "cache", "discard"}
+)
+
+// allocatedOptions are disk options Proxmox derives from the allocation.
+var allocatedOptions = map[string]bool{"size": true, "format": true}
+
+// keepNetIdentity keeps the observed MAC address when the desired device omits it.
+func keepNetIdentity(desired, observed string) string {
+	want, wantErr := properties.ParseNetworkDevice(desired)
+	have, haveErr := properties.ParseNetworkDevice(observed)
+	if wantErr != nil || haveErr != nil || want.MAC != "" || want.Model != have.Model {
+		return desired
+	}
+	want.MAC = have.MAC
+	return want.String()
+}
+
+// keepDiskVolume rewrites a desired allocation to reference the volume already
+// attached, since resending "storage:size" would allocate a new disk. The size
+// and storage of the volume are changed by resizing and moving the disk. It
+// returns false when only those differ, which the config endpoint cannot apply.
+func keepDiskVolume(desired, observed string) (string, bool) {
+	want, wantErr := properties.ParseDisk(desired)
+	have, haveErr := properties.ParseDisk(observed)
+	if wantErr != nil || haveErr != nil {
+		return desired, true
+	}
+	if _, _, ok := want.Allocation(); !ok {
+		return desired, true
+	}
+	if optionsMatch(want.Options()[1:], have.Options()[1:], diskOptionKeys, allocatedOptions) {
+		return "", false
+	}
+	want.File, want.Format, want.Size = have.File, "", have.Size
+	return want.String(), true
+}
+
+// Values assumed by Proxmox for device options absent from the config. Other
+// absent options are equivalent to "0".
+var optionDefaults = map[string]string{
+	"backup":  "1",
+	"cache":   "none",
+	"discard": "ignore",
+	"media":   "disk",
+}
+
+// optionsMatch checks that every desired option is present with the same value,
+// or is absent and the desired value is its default, and that the modeled
+// options the desired device omits are at their default.
+func optionsMatch(want, have properties.List, modeled []string, ignore map[string]bool) bool {
+	for _, p := range want {
+		if ignore[p.Key] {
+			continue
+		}
+		if !sameOption(p.Value, optionValue(have, p.Key)) {
+			return false
+		}
+	}
+	for _, key := range modeled {
+		if _, ok := want.Get(key); ok || ignore[key] {
+			continue
+		}
+		if !sameOption(optionValue(nil, key), optionValue(have, key)) {
+			return false
+		}
+	}
+	return true
+}
+
+// optionValue returns the value of a device option, or its default if absent.
+func optionValue(l properties.List, key string) string {
+	if v, ok := l.Get(key); ok {
+		return v
+	}
+	if v, ok := optionDefaults[key]; ok {
+		return v
+	}
+	return "0"
+}
+
+// sameOption compares option values, treating the spellings of a boolean as equal.
+func sameOption(a, b string) bool {
+	if a == b {
+		return true
+	}
+	x, xok := properties.ParseBool(a)
+	y, yok := properties.ParseBool(b)
+	return xok && yok && x == y
+}
+
+// configString renders a config value as Proxmox would print it, regardless of the JSON type used.
+func configString(cfg map[string]interface{}, key string) string {
+	switch v := cfg[key].(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// sortedKeys returns the keys of desired device options, ordered by bus and index.
+func sortedKeys(options map[string]string) []string {
+	keys := make([]string, 0, len(options))
+	for key := range options {
+		keys = append(keys, key)
+	}
+	sortDeviceKeys(keys)
+	return keys
+}
+
+func intString(v int) string {
+	if v == 0 {
+		return ""
+	}
+	return strconv.Itoa(v)
+}
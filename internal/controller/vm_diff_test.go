@@ -0,0 +1,195 @@
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	proxmoxv1alpha1 "provider-proxmox/api/v1alpha1"
+	"provider-proxmox/internal/proxmoxclient"
+)
+
+func TestDiskMatches(t *testing.T) {
+	tests := []struct {
+		name     string
+		desired  string
+		observed string
+		want     bool
+	}{
+		{name: "allocation matches existing volume", desired: "local-lvm:32", observed: "local-lvm:vm-101-disk-0,size=32G", want: true},
+		{name: "allocation size in MiB", desired: "local-lvm:0.5", observed: "local-lvm:vm-101-disk-0,size=512M", want: true},
+		{name: "allocation on other storage", desired: "ceph:32", observed: "local-lvm:vm-101-disk-0,size=32G", want: false},
+		{name: "size only difference", desired: "local-lvm:64", observed: "local-lvm:vm-101-disk-0,size=32G", want: false},
+		{name: "explicit volume", desired: "file=local-lvm:vm-101-disk-0,iothread=1", observed: "local-lvm:vm-101-disk-0,iothread=1,size=32G", want: true},
+		{name: "other volume", desired: "local-lvm:vm-101-disk-1", observed: "local-lvm:vm-101-disk-0,size=32G", want: false},
+		{name: "boolean option defaults to 0", desired: "local-lvm:32,iothread=0", observed: "local-lvm:vm-101-disk-0,size=32G", want: true},
+		{name: "boolean option set", desired: "local-lvm:32,iothread=1", observed: "local-lvm:vm-101-disk-0,size=32G", want: false},
+		{name: "cdrom", desired: "none,media=cdrom", observed: "none,media=cdrom", want: true},
+		{name: "iothread removed", desired: "local-lvm:32", observed: "local-lvm:vm-101-disk-0,iothread=1,size=32G", want: false},
+		{name: "ssd removed", desired: "local-lvm:32", observed: "local-lvm:vm-101-disk-0,ssd=1,size=32G", want: false},
+		{name: "cache removed", desired: "local-lvm:32", observed: "local-lvm:vm-101-disk-0,cache=writeback,size=32G", want: false},
+		{name: "removed option at default", desired: "local-lvm:32", observed: "local-lvm:vm-101-disk-0,backup=1,cache=none,size=32G", want: true},
+		{name: "cdrom media removed", desired: "local:iso/debian.iso", observed: "local:iso/debian.iso,media=cdrom", want: false},
+		{name: "missing disk", desired: "local-lvm:32", observed: "", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := diskMatches(tt.desired, tt.observed); got != tt.want {
+				t.Errorf("diskMatches(%q, %q) = %v, want %v", tt.desired, tt.observed, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeepDiskVolume(t *testing.T) {
+	tests := []struct {
+		name     string
+		desired  string
+		observed string
+		want     string
+		wantOK   bool
+	}{
+		{name: "option change keeps volume", desired: "local-lvm:32,iothread=1", observed: "local-lvm:vm-101-disk-0,size=32G", want: "local-lvm:vm-101-disk-0,iothread=1,size=32G", wantOK: true},
+		{name: "size only difference", desired: "local-lvm:64", observed: "local-lvm:vm-101-disk-0,size=32G", wantOK: false},
+		{name: "option removal keeps volume", desired: "local-lvm:32", observed: "local-lvm:vm-101-disk-0,iothread=1,size=32G", want: "local-lvm:vm-101-disk-0,size=32G", wantOK: true},
+		{name: "explicit volume sent as is", desired: "local-lvm:vm-101-disk-1", observed: "local-lvm:vm-101-disk-0,size=32G", want: "local-lvm:vm-101-disk-1", wantOK: true},
+		{name: "new disk allocated", desired: "local-lvm:32", observed: "", want: "local-lvm:32", wantOK: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := keepDiskVolume(tt.desired, tt.observed)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("keepDiskVolume(%q, %q) = %q, %v, want %q, %v", tt.desired, tt.observed, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestNetMatches(t *testing.T) {
+	tests := []struct {
+		name     string
+		desired  string
+		observed string
+		want     bool
+	}{
+		{name: "omitted MAC", desired: "virtio,bridge=vmbr0", observed: "virtio=BC:24:11:2E:4F:01,bridge=vmbr0", want: true},
+		{name: "same MAC in other case", desired: "virtio=bc:24:11:2e:4f:01,bridge=vmbr0", observed: "virtio=BC:24:11:2E:4F:01,bridge=vmbr0", want: true},
+		{name: "other MAC", desired: "virtio=BC:24:11:2E:4F:02,bridge=vmbr0", observed: "virtio=BC:24:11:2E:4F:01,bridge=vmbr0", want: false},
+		{name: "other model", desired: "e1000,bridge=vmbr0", observed: "virtio=BC:24:11:2E:4F:01,bridge=vmbr0", want: false},
+		{name: "other bridge", desired: "virtio,bridge=vmbr1", observed: "virtio=BC:24:11:2E:4F:01,bridge=vmbr0", want: false},
+		{name: "firewall defaults to 0", desired: "virtio,bridge=vmbr0,firewall=0", observed: "virtio=BC:24:11:2E:4F:01,bridge=vmbr0", want: true},
+		{name: "firewall enabled", desired: "virtio,bridge=vmbr0,firewall=1", observed: "virtio=BC:24:11:2E:4F:01,bridge=vmbr0", want: false},
+		{name: "VLAN tag removed", desired: "virtio,bridge=vmbr0", observed: "virtio=BC:24:11:2E:4F:01,bridge=vmbr0,tag=20", want: false},
+		{name: "firewall removed", desired: "virtio,bridge=vmbr0", observed: "virtio=BC:24:11:2E:4F:01,bridge=vmbr0,firewall=1", want: false},
+		{name: "rate, MTU and queues removed", desired: "virtio,bridge=vmbr0", observed: "virtio=BC:24:11:2E:4F:01,bridge=vmbr0,mtu=9000,queues=4,rate=100", want: false},
+		{name: "removed option at default", desired: "virtio,bridge=vmbr0", observed: "virtio=BC:24:11:2E:4F:01,bridge=vmbr0,firewall=0", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := netMatches(tt.desired, tt.observed); got != tt.want {
+				t.Errorf("netMatches(%q, %q) = %v, want %v", tt.desired, tt.observed, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffConfig(t *testing.T) {
+	netIndex := 2
+	spec := proxmoxv1alpha1.VirtualMachineSpec{
+		Name:    "test",
+		Memory:  2048,
+		Cores:   1,
+		Sockets: 1,
+		Numa:    new(bool),
+		NetworkDevices: []proxmoxv1alpha1.NetworkDevice{
+			{Bridge: "vmbr0"},
+			{Bridge: "vmbr0", Index: &netIndex},
+		},
+		Disks: []proxmoxv1alpha1.Disk{{Storage: "local-lvm", Size: "32G", IOThread: new(bool)}},
+	}
+	cfg := map[string]interface{}{
+		"name":   "test",
+		"memory": "2048",
+		"net0":   "virtio=BC:24:11:2E:4F:01,bridge=vmbr0",
+		"net2":   "virtio=BC:24:11:2E:4F:02,bridge=vmbr0",
+		"net10":  "e1000=BC:24:11:2E:4F:03,bridge=vmbr2",
+		"scsi0":  "local-lvm:vm-101-disk-0,size=32G",
+	}
+	wantRemoval := []configDiff{{Key: "net10", Observed: "e1000=BC:24:11:2E:4F:03,bridge=vmbr2"}}
+	if diffs := diffConfig(spec, cfg); !reflect.DeepEqual(diffs, wantRemoval) {
+		t.Errorf("diffConfig() = %v, want %v", diffs, wantRemoval)
+	}
+	delete(cfg, "net10")
+
+	spec.Memory = 4096
+	spec.NetworkDevices[0].Bridge = "vmbr1"
+	want := []configDiff{
+		{Key: "memory", Desired: "4096", Observed: "2048"},
+		{Key: "net0", Desired: "virtio,bridge=vmbr1", Observed: "virtio=BC:24:11:2E:4F:01,bridge=vmbr0"},
+	}
+	diffs := diffConfig(spec, cfg)
+	if !reflect.DeepEqual(diffs, want) {
+		t.Fatalf("diffConfig() = %v, want %v", diffs, want)
+	}
+
+	// The MAC address is kept so that Proxmox does not replace the device.
+	payload := updatePayload(cfg, diffs)
+	wantPayload := map[string]interface{}{"memory": "4096", "net0": "virtio=BC:24:11:2E:4F:01,bridge=vmbr1"}
+	if !reflect.DeepEqual(payload, wantPayload) {
+		t.Errorf("updatePayload() = %v, want %v", payload, wantPayload)
+	}
+
+	// Devices dropped from the spec are deleted.
+	spec.NetworkDevices = spec.NetworkDevices[:1]
+	payload = updatePayload(cfg, diffConfig(spec, cfg))
+	wantPayload["net2"] = proxmoxclient.Unset
+	if !reflect.DeepEqual(payload, wantPayload) {
+		t.Errorf("updatePayload() = %v, want %v", payload, wantPayload)
+	}
+
+	// Options dropped from the spec are removed from the device.
+	cfg["net0"] = "virtio=BC:24:11:2E:4F:01,bridge=vmbr1,tag=20"
+	payload = updatePayload(cfg, diffConfig(spec, cfg))
+	if !reflect.DeepEqual(payload, wantPayload) {
+		t.Errorf("updatePayload() = %v, want %v", payload, wantPayload)
+	}
+}
+
+func TestDiffConfigDisks(t *testing.T) {
+	spec := proxmoxv1alpha1.VirtualMachineSpec{
+		Disks: []proxmoxv1alpha1.Disk{
+			{Storage: "local-lvm", Size: "32G"},
+			{Bus: proxmoxv1alpha1.DiskBusVirtIO, Storage: "local-lvm", Size: "8G"},
+		},
+	}
+	cfg := map[string]interface{}{
+		"scsi0":   "local-lvm:vm-101-disk-0,size=32G",
+		"scsi1":   "local-lvm:vm-101-disk-1,size=16G",
+		"unused0": "local-lvm:vm-101-disk-2",
+		"unused1": "local-lvm:vm-101-disk-3",
+	}
+
+	// A new disk is allocated and a disk dropped from the spec is detached,
+	// keeping its volume as an unused disk.
+	payload := updatePayload(cfg, diffConfig(spec, cfg))
+	want := map[string]interface{}{"virtio0": "local-lvm:8", "scsi1": proxmoxclient.Unset}
+	if !reflect.DeepEqual(payload, want) {
+		t.Errorf("updatePayload() = %v, want %v", payload, want)
+	}
+
+	// Without disks in the spec, those of the VM are kept.
+	if diffs := diffConfig(proxmoxv1alpha1.VirtualMachineSpec{}, cfg); len(diffs) != 0 {
+		t.Errorf("diffConfig() = %v, want no differences without disks", diffs)
+	}
+
+	// Unused disks are destroyed only when asked to, and not when attached again.
+	spec.DetachedDisks = proxmoxv1alpha1.DetachedDiskDestroy
+	spec.Disks[1] = proxmoxv1alpha1.Disk{Bus: proxmoxv1alpha1.DiskBusVirtIO, Volume: "local-lvm:vm-101-disk-2"}
+	payload = updatePayload(cfg, diffConfig(spec, cfg))
+	want = map[string]interface{}{"virtio0": "local-lvm:vm-101-disk-2", "scsi1": proxmoxclient.Unset, "unused1": proxmoxclient.Unset}
+	if !reflect.DeepEqual(payload, want) {
+		t.Errorf("updatePayload() = %v, want %v", payload, want)
+	}
+}
@@ -0,0 +1,133 @@
+package controller
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+
+	"github.com/pkg/errors"
+
+	proxmoxv1alpha1 "provider-proxmox/api/v1alpha1"
+	"provider-proxmox/internal/properties"
+)
+
+// observeOnly reports whether the VM is only observed, e.g. to import an
+// existing VM read-only before taking over its management.
+func observeOnly(vm *proxmoxv1alpha1.VirtualMachine) bool {
+	return managementPolicies(vm).ShouldOnlyObserve()
+}
+
+// importSpec sets the spec to the live config of the VM, so that a VM
+// imported read-only keeps its settings once it is managed. Cloud-init
+// settings are left to the spec. It reports whether the spec changed.
+func importSpec(spec *proxmoxv1alpha1.VirtualMachineSpec, node string, cfg map[string]interface{}, power proxmoxv1alpha1.PowerState) bool {
+	old := spec.DeepCopy()
+
+	spec.Node = node
+	spec.Name = configString(cfg, "name")
+	spec.Memory = configInt(cfg, "memory")
+	spec.Cores = configInt(cfg, "cores")
+	spec.CPU = configString(cfg, "cpu")
+	spec.Sockets = configInt(cfg, "sockets")
+	spec.Numa = configBool(cfg, "numa")
+	spec.OSType = configString(cfg, "ostype")
+	spec.ScsiHW = configString(cfg, "scsihw")
+	enabled := agentEnabled(configString(cfg, "agent"))
+	spec.Agent = &enabled
+	spec.PowerState = power
+	spec.NetworkDevices = importNetworks(cfg)
+	spec.Disks = importDisks(cfg)
+
+	return !reflect.DeepEqual(old, spec)
+}
+
+// importNetworks converts the network devices of a VM config for the spec.
+func importNetworks(cfg map[string]interface{}) []proxmoxv1alpha1.NetworkDevice {
+	var devices []proxmoxv1alpha1.NetworkDevice
+	for _, key := range matchingKeys(cfg, networkKeyRe) {
+		n, err := properties.ParseNetworkDevice(configString(cfg, key))
+		if err != nil {
+			continue
+		}
+		index, _ := strconv.Atoi(networkKeyRe.FindStringSubmatch(key)[1])
+		d := proxmoxv1alpha1.NetworkDevice{
+			Index:      &index,
+			Model:      n.Model,
+			MACAddress: n.MAC,
+			Bridge:     n.Bridge,
+			Firewall:   n.Firewall,
+			LinkDown:   n.LinkDown,
+			Rate:       n.Rate,
+			MTU:        n.MTU,
+			Queues:     n.Queues,
+		}
+		if n.Tag != 0 {
+			tag := n.Tag
+			d.VLANTag = &tag
+		}
+		devices = append(devices, d)
+	}
+	return devices
+}
+
+// importDisks converts the disks of a VM config for the spec. Disks attach
+// their existing volume, and the cloud-init drive is left out.
+func importDisks(cfg map[string]interface{}) []proxmoxv1alpha1.Disk {
+	var disks []proxmoxv1alpha1.Disk
+	for _, key := range matchingKeys(cfg, diskKeyRe) {
+		option := configString(cfg, key)
+		d, err := properties.ParseDisk(option)
+		if err != nil || isCloudInitDrive(option) {
+			continue
+		}
+		m := diskKeyRe.FindStringSubmatch(key)
+		index, _ := strconv.Atoi(m[2])
+		disks = append(disks, proxmoxv1alpha1.Disk{
+			Bus:      proxmoxv1alpha1.DiskBus(m[1]),
+			Index:    &index,
+			Volume:   d.File,
+			Media:    d.Media,
+			Format:   d.Format,
+			Cache:    d.Cache,
+			IOThread: d.IOThread,
+			Discard:  d.Discard,
+			SSD:      d.SSD,
+			Backup:   d.Backup,
+		})
+	}
+	return disks
+}
+
+// configInt returns a numeric option of a VM config, zero if unset.
+func configInt(cfg map[string]interface{}, key string) int {
+	v, _ := strconv.Atoi(configString(cfg, key))
+	return v
+}
+
+// configBool returns a boolean option of a VM config, nil if unset.
+func configBool(cfg map[string]interface{}, key string) *bool {
+	v, ok := properties.ParseBool(configString(cfg, key))
+	if !ok {
+		return nil
+	}
+	return &v
+}
+
+// importVM persists the spec of an observed-only VM taken from its live
+// config, along with a VMID or external name set during Observe. The
+// reconciler only persists late-initialized specs when the management
+// policies allow LateInitialize, so the spec is updated here, on a copy to
+// keep the status observed so far.
+func (e *external) importVM(ctx context.Context, vm *proxmoxv1alpha1.VirtualMachine, node string, cfg map[string]interface{}, lateInit bool) error {
+	imported := vm.DeepCopy()
+	if !importSpec(&imported.Spec, node, cfg, vm.Status.AtProvider.PowerState) && !lateInit {
+		return nil
+	}
+	e.log.Info("Importing VM spec from Proxmox", "VMID", vm.Spec.VMID, "Node", node)
+	if err := e.kube.Update(ctx, imported); err != nil {
+		return errors.Wrap(err, "cannot import VM spec")
+	}
+	vm.ObjectMeta = imported.ObjectMeta
+	vm.Spec = imported.Spec
+	return nil
+}
@@ -0,0 +1,88 @@
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+
+	proxmoxv1alpha1 "provider-proxmox/api/v1alpha1"
+)
+
+func TestImportSpec(t *testing.T) {
+	cfg := map[string]interface{}{
+		"name":    "web",
+		"memory":  "4096",
+		"cores":   float64(2),
+		"sockets": "1",
+		"cpu":     "host",
+		"numa":    float64(0),
+		"ostype":  "l26",
+		"scsihw":  "virtio-scsi-single",
+		"agent":   "1,fstrim_cloned_disks=1",
+		"net0":    "virtio=BC:24:11:2A:3B:4C,bridge=vmbr0,tag=20,firewall=1",
+		"scsi0":   "local-lvm:vm-105-disk-0,iothread=1,size=32G",
+		"ide2":    "local-lvm:vm-105-cloudinit,media=cdrom",
+		"ide0":    "none,media=cdrom",
+		"unused0": "local-lvm:vm-105-disk-1",
+	}
+	index := func(i int) *int { return &i }
+	flag := func(b bool) *bool { return &b }
+	want := proxmoxv1alpha1.VirtualMachineSpec{
+		VMID:       105,
+		Node:       "pve",
+		Name:       "web",
+		Memory:     4096,
+		Cores:      2,
+		CPU:        "host",
+		Sockets:    1,
+		Numa:       flag(false),
+		OSType:     "l26",
+		ScsiHW:     "virtio-scsi-single",
+		Agent:      flag(true),
+		PowerState: proxmoxv1alpha1.PowerStateRunning,
+		NetworkDevices: []proxmoxv1alpha1.NetworkDevice{
+			{Index: index(0), Model: "virtio", MACAddress: "BC:24:11:2A:3B:4C", Bridge: "vmbr0", VLANTag: index(20), Firewall: flag(true)},
+		},
+		Disks: []proxmoxv1alpha1.Disk{
+			{Bus: proxmoxv1alpha1.DiskBusIDE, Index: index(0), Volume: "none", Media: "cdrom"},
+			{Bus: proxmoxv1alpha1.DiskBusSCSI, Index: index(0), Volume: "local-lvm:vm-105-disk-0", IOThread: flag(true)},
+		},
+	}
+
+	spec := proxmoxv1alpha1.VirtualMachineSpec{VMID: 105, Memory: 2048}
+	if !importSpec(&spec, "pve", cfg, proxmoxv1alpha1.PowerStateRunning) {
+		t.Error("importSpec() = false, want the spec changed")
+	}
+	if !reflect.DeepEqual(spec, want) {
+		t.Errorf("importSpec() spec = %+v, want %+v", spec, want)
+	}
+	if importSpec(&spec, "pve", cfg, proxmoxv1alpha1.PowerStateRunning) {
+		t.Error("importSpec() = true for an imported spec")
+	}
+	if diffs := diffConfig(spec, cfg); len(diffs) != 0 {
+		t.Errorf("diffConfig() = %v for an imported spec, want none", diffs)
+	}
+}
+
+func TestObserveOnly(t *testing.T) {
+	tests := []struct {
+		name     string
+		policies xpv1.ManagementPolicies
+		want     bool
+	}{
+		{name: "default"},
+		{name: "all", policies: xpv1.ManagementPolicies{xpv1.ManagementActionAll}},
+		{name: "observe", policies: xpv1.ManagementPolicies{xpv1.ManagementActionObserve}, want: true},
+		{name: "observe and update", policies: xpv1.ManagementPolicies{xpv1.ManagementActionObserve, xpv1.ManagementActionUpdate}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vm := &proxmoxv1alpha1.VirtualMachine{Spec: proxmoxv1alpha1.VirtualMachineSpec{ManagementPolicies: tt.policies}}
+			if got := observeOnly(vm); got != tt.want {
+				t.Errorf("observeOnly() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
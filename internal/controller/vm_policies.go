@@ -0,0 +1,43 @@
+package controller
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+
+	proxmoxv1alpha1 "provider-proxmox/api/v1alpha1"
+)
+
+// managementPolicies resolves the actions the management and deletion
+// policies of the VM allow, as the reconciler does.
+func managementPolicies(vm *proxmoxv1alpha1.VirtualMachine) managed.ManagementPoliciesChecker {
+	return managed.NewManagementPoliciesResolver(true, vm.GetManagementPolicies(), vm.GetDeletionPolicy())
+}
+
+// vmFinalizer removes the finalizer the controller adds to VMs along with the
+// finalizer of the reconciler. The reconciler removes its finalizer without
+// calling Observe or Delete when a VM is orphaned, i.e. when the resource is
+// deleted while the policies do not allow deleting the VM in Proxmox.
+type vmFinalizer struct {
+	resource.Finalizer
+	client client.Client
+}
+
+func newVMFinalizer(c client.Client) vmFinalizer {
+	return vmFinalizer{Finalizer: resource.NewAPIFinalizer(c, managed.FinalizerName), client: c}
+}
+
+// RemoveFinalizer removes both finalizers, leaving the VM alone.
+func (f vmFinalizer) RemoveFinalizer(ctx context.Context, obj resource.Object) error {
+	if !HasFinalizer(obj, finalizerName) {
+		return f.Finalizer.RemoveFinalizer(ctx, obj)
+	}
+	RemoveFinalizer(obj, finalizerName)
+	meta.RemoveFinalizer(obj, managed.FinalizerName)
+	return errors.Wrap(resource.IgnoreNotFound(f.client.Update(ctx, obj)), "cannot remove finalizers")
+}
@@ -0,0 +1,92 @@
+package controller
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+
+	proxmoxv1alpha1 "provider-proxmox/api/v1alpha1"
+)
+
+func TestManagementPolicies(t *testing.T) {
+	tests := []struct {
+		name         string
+		policies     xpv1.ManagementPolicies
+		deletion     xpv1.DeletionPolicy
+		wantDelete   bool
+		wantLateInit bool
+	}{
+		{name: "all", policies: xpv1.ManagementPolicies{xpv1.ManagementActionAll}, deletion: xpv1.DeletionDelete, wantDelete: true, wantLateInit: true},
+		{name: "orphan", policies: xpv1.ManagementPolicies{xpv1.ManagementActionAll}, deletion: xpv1.DeletionOrphan, wantLateInit: true},
+		{name: "observe", policies: xpv1.ManagementPolicies{xpv1.ManagementActionObserve}, deletion: xpv1.DeletionDelete},
+		{
+			name:     "no late initialization",
+			policies: xpv1.ManagementPolicies{xpv1.ManagementActionObserve, xpv1.ManagementActionCreate, xpv1.ManagementActionUpdate, xpv1.ManagementActionDelete},
+			deletion: xpv1.DeletionDelete, wantDelete: true,
+		},
+		{
+			name:     "no delete",
+			policies: xpv1.ManagementPolicies{xpv1.ManagementActionObserve, xpv1.ManagementActionCreate, xpv1.ManagementActionUpdate, xpv1.ManagementActionLateInitialize},
+			deletion: xpv1.DeletionDelete, wantLateInit: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vm := &proxmoxv1alpha1.VirtualMachine{Spec: proxmoxv1alpha1.VirtualMachineSpec{ManagementPolicies: tt.policies, DeletionPolicy: tt.deletion}}
+			p := managementPolicies(vm)
+			if got := p.ShouldDelete(); got != tt.wantDelete {
+				t.Errorf("ShouldDelete() = %v, want %v", got, tt.wantDelete)
+			}
+			if got := p.ShouldLateInitialize(); got != tt.wantLateInit {
+				t.Errorf("ShouldLateInitialize() = %v, want %v", got, tt.wantLateInit)
+			}
+		})
+	}
+}
+
+// updateRecorder records the objects updated through it.
+type updateRecorder struct {
+	client.Client
+	updated []client.Object
+}
+
+func (r *updateRecorder) Update(_ context.Context, obj client.Object, _ ...client.UpdateOption) error {
+	r.updated = append(r.updated, obj.DeepCopyObject().(client.Object))
+	return nil
+}
+
+func TestVMFinalizer(t *testing.T) {
+	tests := []struct {
+		name       string
+		finalizers []string
+		want       []string
+	}{
+		{name: "both finalizers", finalizers: []string{managed.FinalizerName, finalizerName, "other"}, want: []string{"other"}},
+		{name: "controller finalizer only", finalizers: []string{finalizerName}, want: []string{}},
+		{name: "reconciler finalizer only", finalizers: []string{managed.FinalizerName}, want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vm := &proxmoxv1alpha1.VirtualMachine{ObjectMeta: metav1.ObjectMeta{Name: "test", Finalizers: tt.finalizers}}
+			kube := &updateRecorder{}
+
+			if err := newVMFinalizer(kube).RemoveFinalizer(context.Background(), vm); err != nil {
+				t.Fatalf("RemoveFinalizer() error = %v", err)
+			}
+			if len(kube.updated) != 1 {
+				t.Fatalf("RemoveFinalizer() updated the object %d times, want once", len(kube.updated))
+			}
+			if got := kube.updated[0].GetFinalizers(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("RemoveFinalizer() stored finalizers %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
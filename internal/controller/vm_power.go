@@ -0,0 +1,115 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	proxmoxv1alpha1 "provider-proxmox/api/v1alpha1"
+)
+
+// Actions changing the power state of a VM.
+const (
+	powerStart     = "start"
+	powerShutdown  = "shutdown"
+	powerStop      = "stop"
+	powerPause     = "pause"
+	powerHibernate = "hibernate"
+	powerResume    = "resume"
+	powerReboot    = "reboot"
+)
+
+// observedPowerState derives the power state of a VM from its status and
+// config. A VM suspended to disk is stopped and locked with its memory saved.
+func observedPowerState(status *proxmoxv1alpha1.VirtualMachineStatus, cfg map[string]interface{}) proxmoxv1alpha1.PowerState {
+	switch {
+	case status.Status == "running" && (status.QMPStatus == "paused" || status.QMPStatus == "suspended"):
+		return proxmoxv1alpha1.PowerStatePaused
+	case status.Status == "running":
+		return proxmoxv1alpha1.PowerStateRunning
+	case configString(cfg, "lock") == "suspended" || configString(cfg, "vmstate") != "":
+		return proxmoxv1alpha1.PowerStateSuspended
+	default:
+		return proxmoxv1alpha1.PowerStateStopped
+	}
+}
+
+// powerAction returns the next action bringing a VM from its observed power
+// state towards the desired one, or an empty string when it is there. Some
+// changes take two steps, e.g. a VM suspended to disk is started before it
+// can be shut down.
+func powerAction(observed, desired proxmoxv1alpha1.PowerState) string {
+	if desired == "" || observed == "" || observed == desired {
+		return ""
+	}
+	switch desired {
+	case proxmoxv1alpha1.PowerStateRunning:
+		if observed == proxmoxv1alpha1.PowerStatePaused {
+			return powerResume
+		}
+		return powerStart
+	case proxmoxv1alpha1.PowerStateStopped:
+		switch observed {
+		case proxmoxv1alpha1.PowerStateRunning:
+			return powerShutdown
+		case proxmoxv1alpha1.PowerStatePaused:
+			// A paused guest cannot shut down by itself.
+			return powerStop
+		}
+		return powerStart
+	case proxmoxv1alpha1.PowerStatePaused:
+		if observed == proxmoxv1alpha1.PowerStateRunning {
+			return powerPause
+		}
+		return powerStart
+	case proxmoxv1alpha1.PowerStateSuspended:
+		switch observed {
+		case proxmoxv1alpha1.PowerStateRunning:
+			return powerHibernate
+		case proxmoxv1alpha1.PowerStatePaused:
+			return powerResume
+		}
+		return powerStart
+	}
+	return ""
+}
+
+// powerDiff describes a VM that is not in its desired power state.
+func powerDiff(vm *proxmoxv1alpha1.VirtualMachine) string {
+	if powerAction(vm.Status.AtProvider.PowerState, vm.Spec.PowerState) == "" {
+		return ""
+	}
+	return fmt.Sprintf("powerState: want %q, got %q", vm.Spec.PowerState, vm.Status.AtProvider.PowerState)
+}
+
+// changePowerState runs a power action on the VM and returns the UPID of the
+// task started by Proxmox.
+func (e *external) changePowerState(ctx context.Context, vm *proxmoxv1alpha1.VirtualMachine, node, action string) (string, error) {
+	e.log.Info("Changing VM power state", "VMID", vm.Spec.VMID, "Action", action, "PowerState", vm.Spec.PowerState)
+	switch action {
+	case powerStart:
+		return e.client.Start(ctx, node, vm.Spec.VMID)
+	case powerShutdown:
+		return e.client.Shutdown(ctx, node, vm.Spec.VMID, shutdownTimeout(vm))
+	case powerStop:
+		return e.client.Stop(ctx, node, vm.Spec.VMID)
+	case powerPause:
+		return e.client.Suspend(ctx, node, vm.Spec.VMID, false)
+	case powerHibernate:
+		return e.client.Suspend(ctx, node, vm.Spec.VMID, true)
+	case powerResume:
+		return e.client.Resume(ctx, node, vm.Spec.VMID)
+	case powerReboot:
+		return e.client.Reboot(ctx, node, vm.Spec.VMID, shutdownTimeout(vm))
+	}
+	return "", nil
+}
+
+// shutdownTimeout returns the graceful shutdown timeout of the VM, zero
+// selecting the Proxmox default.
+func shutdownTimeout(vm *proxmoxv1alpha1.VirtualMachine) time.Duration {
+	if vm.Spec.ShutdownTimeout == nil {
+		return 0
+	}
+	return vm.Spec.ShutdownTimeout.Duration
+}
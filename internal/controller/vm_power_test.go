@@ -0,0 +1,64 @@
+package controller
+
+import (
+	"testing"
+
+	proxmoxv1alpha1 "provider-proxmox/api/v1alpha1"
+)
+
+func TestObservedPowerState(t *testing.T) {
+	tests := []struct {
+		name   string
+		status proxmoxv1alpha1.VirtualMachineStatus
+		cfg    map[string]interface{}
+		want   proxmoxv1alpha1.PowerState
+	}{
+		{name: "running", status: proxmoxv1alpha1.VirtualMachineStatus{Status: "running", QMPStatus: "running"}, want: proxmoxv1alpha1.PowerStateRunning},
+		{name: "paused", status: proxmoxv1alpha1.VirtualMachineStatus{Status: "running", QMPStatus: "paused"}, want: proxmoxv1alpha1.PowerStatePaused},
+		{name: "stopped", status: proxmoxv1alpha1.VirtualMachineStatus{Status: "stopped"}, want: proxmoxv1alpha1.PowerStateStopped},
+		{name: "suspended to disk", status: proxmoxv1alpha1.VirtualMachineStatus{Status: "stopped"}, cfg: map[string]interface{}{"lock": "suspended", "vmstate": "local-lvm:vm-101-state-suspend-2024-05-01"}, want: proxmoxv1alpha1.PowerStateSuspended},
+		{name: "locked by a backup", status: proxmoxv1alpha1.VirtualMachineStatus{Status: "stopped"}, cfg: map[string]interface{}{"lock": "backup"}, want: proxmoxv1alpha1.PowerStateStopped},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := observedPowerState(&tt.status, tt.cfg); got != tt.want {
+				t.Errorf("observedPowerState() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPowerAction(t *testing.T) {
+	const (
+		running   = proxmoxv1alpha1.PowerStateRunning
+		stopped   = proxmoxv1alpha1.PowerStateStopped
+		paused    = proxmoxv1alpha1.PowerStatePaused
+		suspended = proxmoxv1alpha1.PowerStateSuspended
+	)
+	tests := []struct {
+		observed, desired proxmoxv1alpha1.PowerState
+		want              string
+	}{
+		{observed: running, desired: "", want: ""},
+		{observed: "", desired: running, want: ""},
+		{observed: running, desired: running, want: ""},
+		{observed: stopped, desired: running, want: powerStart},
+		{observed: paused, desired: running, want: powerResume},
+		{observed: suspended, desired: running, want: powerStart},
+		{observed: running, desired: stopped, want: powerShutdown},
+		{observed: paused, desired: stopped, want: powerStop},
+		{observed: suspended, desired: stopped, want: powerStart},
+		{observed: running, desired: paused, want: powerPause},
+		{observed: stopped, desired: paused, want: powerStart},
+		{observed: running, desired: suspended, want: powerHibernate},
+		{observed: paused, desired: suspended, want: powerResume},
+		{observed: stopped, desired: suspended, want: powerStart},
+	}
+
+	for _, tt := range tests {
+		if got := powerAction(tt.observed, tt.desired); got != tt.want {
+			t.Errorf("powerAction(%q, %q) = %q, want %q", tt.observed, tt.desired, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	proxmoxv1alpha1 "provider-proxmox/api/v1alpha1"
+	"provider-proxmox/internal/cron"
+)
+
+// defaultMaintenanceWindow is the duration of a maintenance window that does
+// not set one.
+const defaultMaintenanceWindow = time.Hour
+
+// validateRebootPolicy checks the schedule of a maintenance window.
+func validateRebootPolicy(policy *proxmoxv1alpha1.RebootPolicy) error {
+	if policy == nil || policy.Policy != proxmoxv1alpha1.RebootMaintenanceWindow {
+		return nil
+	}
+	_, err := cron.Parse(policy.Schedule)
+	return errors.Wrap(err, "invalid reboot policy")
+}
+
+// rebootDue reports whether the reboot policy allows rebooting the VM at now.
+// Otherwise it returns the start of the next maintenance window, if any.
+func rebootDue(policy *proxmoxv1alpha1.RebootPolicy, now time.Time) (bool, time.Time) {
+	if policy == nil {
+		return false, time.Time{}
+	}
+	switch policy.Policy {
+	case proxmoxv1alpha1.RebootAutomatic:
+		return true, time.Time{}
+	case proxmoxv1alpha1.RebootMaintenanceWindow:
+		schedule, err := cron.Parse(policy.Schedule)
+		if err != nil {
+			return false, time.Time{}
+		}
+		duration := defaultMaintenanceWindow
+		if policy.Duration != nil {
+			duration = policy.Duration.Duration
+		}
+		now = now.UTC()
+		if schedule.Active(now, duration) {
+			return true, time.Time{}
+		}
+		return false, schedule.Next(now)
+	}
+	return false, time.Time{}
+}
+
+// pendingKeys returns the config keys Proxmox keeps pending until the next
+// start of the VM, which only a running VM has.
+func (e *external) pendingKeys(ctx context.Context, vm *proxmoxv1alpha1.VirtualMachine, node string) ([]string, error) {
+	if vm.Status.Status != "running" {
+		return nil, nil
+	}
+	pending, err := e.client.PendingChanges(ctx, node, vm.Spec.VMID)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(pending))
+	for _, p := range pending {
+		keys = append(keys, p.Key)
+	}
+	return keys, nil
+}
+
+// observePending records the pending changes of the VM in its status and
+// conditions. It returns a description of the changes when the reboot policy
+// asks to reboot the VM now to apply them.
+func (e *external) observePending(ctx context.Context, vm *proxmoxv1alpha1.VirtualMachine, node string, now time.Time) (string, error) {
+	keys, err := e.pendingKeys(ctx, vm, node)
+	if err != nil {
+		return "", err
+	}
+	vm.Status.AtProvider.PendingChanges = keys
+	if len(keys) == 0 {
+		vm.SetConditions(proxmoxv1alpha1.NoPendingReboot())
+		return "", nil
+	}
+
+	msg := fmt.Sprintf("changes to %s apply on the next reboot", strings.Join(keys, ", "))
+	due, next := rebootDue(vm.Spec.RebootPolicy, now)
+	switch {
+	case due && vm.Status.AtProvider.PowerState == proxmoxv1alpha1.PowerStateRunning:
+		vm.SetConditions(proxmoxv1alpha1.PendingReboot(msg + "; rebooting"))
+		return "pending reboot: " + strings.Join(keys, ", "), nil
+	case !next.IsZero():
+		vm.SetConditions(proxmoxv1alpha1.PendingReboot(msg + "; rebooting in the maintenance window at " + next.Format(time.RFC3339)))
+	default:
+		vm.SetConditions(proxmoxv1alpha1.PendingReboot(msg))
+	}
+	return "", nil
+}
+
+// needsReboot reports whether a running VM is to be rebooted now to apply
+// pending changes. An update just applied may have left new changes pending.
+func (e *external) needsReboot(ctx context.Context, vm *proxmoxv1alpha1.VirtualMachine, node string, updated bool) (bool, error) {
+	if vm.Status.AtProvider.PowerState != proxmoxv1alpha1.PowerStateRunning {
+		return false, nil
+	}
+	if due, _ := rebootDue(vm.Spec.RebootPolicy, time.Now()); !due {
+		return false, nil
+	}
+	if !updated {
+		return len(vm.Status.AtProvider.PendingChanges) > 0, nil
+	}
+	keys, err := e.pendingKeys(ctx, vm, node)
+	if err != nil {
+		return false, err
+	}
+	vm.Status.AtProvider.PendingChanges = keys
+	return len(keys) > 0, nil
+}
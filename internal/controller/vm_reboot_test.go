@@ -0,0 +1,58 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	proxmoxv1alpha1 "provider-proxmox/api/v1alpha1"
+)
+
+func TestRebootDue(t *testing.T) {
+	saturday := time.Date(2024, 5, 4, 2, 30, 0, 0, time.UTC)
+	window := func(schedule string, duration time.Duration) *proxmoxv1alpha1.RebootPolicy {
+		p := &proxmoxv1alpha1.RebootPolicy{Policy: proxmoxv1alpha1.RebootMaintenanceWindow, Schedule: schedule}
+		if duration > 0 {
+			p.Duration = &metav1.Duration{Duration: duration}
+		}
+		return p
+	}
+
+	tests := []struct {
+		name     string
+		policy   *proxmoxv1alpha1.RebootPolicy
+		now      time.Time
+		wantDue  bool
+		wantNext time.Time
+	}{
+		{name: "no policy", now: saturday},
+		{name: "never", policy: &proxmoxv1alpha1.RebootPolicy{Policy: proxmoxv1alpha1.RebootNever}, now: saturday},
+		{name: "automatic", policy: &proxmoxv1alpha1.RebootPolicy{Policy: proxmoxv1alpha1.RebootAutomatic}, now: saturday, wantDue: true},
+		{name: "within window", policy: window("0 2 * * 6", 0), now: saturday, wantDue: true},
+		{name: "after window", policy: window("0 2 * * 6", 0), now: saturday.Add(time.Hour), wantNext: time.Date(2024, 5, 11, 2, 0, 0, 0, time.UTC)},
+		{name: "short window", policy: window("0 2 * * 6", 15*time.Minute), now: saturday, wantNext: time.Date(2024, 5, 11, 2, 0, 0, 0, time.UTC)},
+		{name: "evaluated in UTC", policy: window("0 2 * * 6", 0), now: saturday.In(time.FixedZone("CEST", 2*3600)), wantDue: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			due, next := rebootDue(tt.policy, tt.now)
+			if due != tt.wantDue || !next.Equal(tt.wantNext) {
+				t.Errorf("rebootDue() = %v, %s, want %v, %s", due, next, tt.wantDue, tt.wantNext)
+			}
+		})
+	}
+}
+
+func TestValidateRebootPolicy(t *testing.T) {
+	if err := validateRebootPolicy(&proxmoxv1alpha1.RebootPolicy{Policy: proxmoxv1alpha1.RebootMaintenanceWindow, Schedule: "0 2 * * 6"}); err != nil {
+		t.Errorf("validateRebootPolicy() error = %v", err)
+	}
+	if err := validateRebootPolicy(&proxmoxv1alpha1.RebootPolicy{Policy: proxmoxv1alpha1.RebootMaintenanceWindow, Schedule: "0 2 * *"}); err == nil {
+		t.Error("validateRebootPolicy() error = nil for an invalid schedule")
+	}
+	if err := validateRebootPolicy(nil); err != nil {
+		t.Errorf("validateRebootPolicy(nil) error = %v", err)
+	}
+}
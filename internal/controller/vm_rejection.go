@@ -0,0 +1,66 @@
+package controller
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+
+	proxmoxv1alpha1 "provider-proxmox/api/v1alpha1"
+	"provider-proxmox/internal/proxmoxclient"
+)
+
+// rejectedRetryInterval is how long a request Proxmox refused is left alone when
+// the spec does not change, e.g. while privileges are fixed on the Proxmox side.
+const rejectedRetryInterval = 10 * time.Minute
+
+// isTerminal checks if an error will not resolve by sending the same request
+// again: Proxmox rejected the parameters or the credentials lack privileges.
+func isTerminal(err error) bool {
+	if proxmoxclient.IsRetryable(err) || proxmoxclient.IsLocked(err) {
+		return false
+	}
+	return proxmoxclient.IsValidation(err) || proxmoxclient.IsUnauthorized(err)
+}
+
+// reject records a terminal failure on the VM, so that later reconciles do not
+// repeat the request until the spec changes or rejectedRetryInterval elapsed.
+// Other errors are left to the reconciler, which retries them with backoff.
+func reject(vm *proxmoxv1alpha1.VirtualMachine, err error) bool {
+	if !isTerminal(err) {
+		return false
+	}
+	setRejected(vm, err)
+	return true
+}
+
+// setRejected records that the current generation of the spec cannot be applied.
+func setRejected(vm *proxmoxv1alpha1.VirtualMachine, err error) {
+	// Drop the previous condition so that a repeated rejection restarts the
+	// interval; SetConditions keeps the transition time of an equal condition.
+	c := proxmoxv1alpha1.Rejected(vm.Generation, err.Error())
+	conditions := make([]xpv1.Condition, 0, len(vm.Status.Conditions))
+	for _, existing := range vm.Status.Conditions {
+		if existing.Type != c.Type {
+			conditions = append(conditions, existing)
+		}
+	}
+	vm.Status.Conditions = conditions
+	vm.SetConditions(c)
+}
+
+// rejection returns the failure recorded for the current generation of the VM
+// while it should not be retried yet. The message does not change between
+// reconciles, so reporting it does not trigger a new one.
+func rejection(vm *proxmoxv1alpha1.VirtualMachine) error {
+	c := vm.GetCondition(proxmoxv1alpha1.TypeUpToDate)
+	if c.Reason != proxmoxv1alpha1.ReasonRejected || c.ObservedGeneration != vm.Generation {
+		return nil
+	}
+	retryAt := c.LastTransitionTime.Add(rejectedRetryInterval)
+	if time.Now().After(retryAt) {
+		return nil
+	}
+	return errors.Errorf("%s; not retrying before %s unless the spec changes", c.Message, retryAt.UTC().Format(time.RFC3339))
+}
@@ -0,0 +1,48 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	proxmoxv1alpha1 "provider-proxmox/api/v1alpha1"
+	"provider-proxmox/internal/proxmoxclient"
+)
+
+func TestRejection(t *testing.T) {
+	invalid := &proxmoxclient.APIError{Method: http.MethodPost, Path: "/api2/json/nodes/pve/qemu", StatusCode: http.StatusBadRequest, Message: "Parameter verification failed."}
+	busy := &proxmoxclient.APIError{Method: http.MethodPost, Path: "/api2/json/nodes/pve/qemu", StatusCode: http.StatusServiceUnavailable}
+
+	vm := &proxmoxv1alpha1.VirtualMachine{ObjectMeta: metav1.ObjectMeta{Generation: 2}}
+	if reject(vm, fmt.Errorf("cannot create VM: %w", busy)) {
+		t.Fatal("reject() recorded a retryable error")
+	}
+	if err := rejection(vm); err != nil {
+		t.Fatalf("rejection() = %v without a recorded failure", err)
+	}
+
+	if !reject(vm, fmt.Errorf("cannot create VM: %w", invalid)) {
+		t.Fatal("reject() did not record a validation error")
+	}
+	if err := rejection(vm); err == nil {
+		t.Error("rejection() = nil for the rejected generation")
+	}
+
+	// A repeated rejection restarts the retry interval.
+	vm.Status.Conditions[0].LastTransitionTime = metav1.NewTime(time.Now().Add(-2 * rejectedRetryInterval))
+	if err := rejection(vm); err != nil {
+		t.Errorf("rejection() = %v after the retry interval", err)
+	}
+	reject(vm, fmt.Errorf("cannot create VM: %w", invalid))
+	if err := rejection(vm); err == nil || len(vm.Status.Conditions) != 1 {
+		t.Errorf("reject() did not restart the retry interval: %+v", vm.Status.Conditions)
+	}
+
+	vm.Generation = 3
+	if err := rejection(vm); err != nil {
+		t.Errorf("rejection() = %v after the spec changed", err)
+	}
+}
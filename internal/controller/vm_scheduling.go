@@ -0,0 +1,108 @@
+package controller
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	proxmoxv1alpha1 "provider-proxmox/api/v1alpha1"
+	"provider-proxmox/internal/scheduler"
+)
+
+// schedulingFor merges the scheduling settings of the VM over those of the ProviderConfig.
+func (e *external) schedulingFor(vm *proxmoxv1alpha1.VirtualMachine) proxmoxv1alpha1.Scheduling {
+	var s proxmoxv1alpha1.Scheduling
+	if e.scheduling != nil {
+		s = *e.scheduling
+	}
+	if vs := vm.Spec.Scheduling; vs != nil {
+		if vs.Policy != "" {
+			s.Policy = vs.Policy
+		}
+		if vs.MaxCPUUsagePercent != 0 {
+			s.MaxCPUUsagePercent = vs.MaxCPUUsagePercent
+		}
+	}
+	return s
+}
+
+// scheduleNode picks a node for the VM from the current cluster resources.
+func (e *external) scheduleNode(ctx context.Context, vm *proxmoxv1alpha1.VirtualMachine) (string, error) {
+	resources, err := e.client.ClusterResources(ctx, "")
+	if err != nil {
+		return "", errors.Wrap(err, "cannot list cluster resources")
+	}
+
+	nodes := map[string]*scheduler.Node{}
+	for _, r := range resources {
+		if r.Type == "node" {
+			nodes[r.Node] = &scheduler.Node{
+				Name:     r.Node,
+				Online:   r.Status == "online",
+				CPU:      r.CPU,
+				Mem:      r.Mem,
+				MaxMem:   r.MaxMem,
+				Storages: map[string]bool{},
+				Bridges:  map[string]bool{},
+			}
+		}
+	}
+	for _, r := range resources {
+		n, ok := nodes[r.Node]
+		if !ok {
+			continue
+		}
+		switch r.Type {
+		case "qemu", "lxc":
+			n.VMs++
+		case "storage":
+			if r.Status == "available" {
+				n.Storages[r.Storage] = true
+			}
+		}
+	}
+
+	req := vmRequirements(vm)
+	if s := e.schedulingFor(vm); s.MaxCPUUsagePercent != 0 {
+		req.MaxCPUUsage = float64(s.MaxCPUUsagePercent) / 100
+	}
+
+	candidates := make([]scheduler.Node, 0, len(nodes))
+	for _, n := range nodes {
+		if n.Online && len(req.Bridges) > 0 {
+			bridges, err := e.client.NodeBridges(ctx, n.Name)
+			if err != nil {
+				return "", errors.Wrapf(err, "cannot list bridges of node %s", n.Name)
+			}
+			for _, b := range bridges {
+				n.Bridges[b] = true
+			}
+		}
+		candidates = append(candidates, *n)
+	}
+
+	return scheduler.Select(candidates, req, e.schedulingFor(vm).Policy)
+}
+
+// vmRequirements derives the memory, storages and bridges a node must provide for the VM.
+func vmRequirements(vm *proxmoxv1alpha1.VirtualMachine) scheduler.Requirements {
+	req := scheduler.Requirements{Memory: int64(vm.Spec.Memory) * 1024 * 1024}
+
+	for i := range vm.Spec.Disks {
+		if storage := diskDevice(&vm.Spec.Disks[i]).Storage(); storage != "" {
+			req.Storages = append(req.Storages, storage)
+		}
+	}
+
+	if vm.Spec.Clone != nil && vm.Spec.Clone.Storage != "" {
+		req.Storages = append(req.Storages, vm.Spec.Clone.Storage)
+	}
+
+	for _, n := range vm.Spec.NetworkDevices {
+		if n.Bridge != "" {
+			req.Bridges = append(req.Bridges, n.Bridge)
+		}
+	}
+
+	return req
+}
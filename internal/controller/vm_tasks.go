@@ -0,0 +1,78 @@
+package controller
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	proxmoxv1alpha1 "provider-proxmox/api/v1alpha1"
+	"provider-proxmox/internal/proxmoxclient"
+)
+
+// Operations recorded alongside an in-flight task.
+const (
+	taskOpCreate = "create"
+	taskOpUpdate = "update"
+	taskOpMove   = "move"
+	taskOpResize = "resize"
+	taskOpPower  = "power"
+	taskOpDelete = "delete"
+)
+
+// taskLogLines is the number of task log lines reported when a task fails.
+const taskLogLines = 10
+
+// trackTask records a task started by Proxmox so later reconciles wait for it.
+// Synchronous calls return no UPID and leave nothing to track.
+func trackTask(vm *proxmoxv1alpha1.VirtualMachine, upid, operation string) {
+	if upid == "" {
+		return
+	}
+	vm.Status.AtProvider.Task = &proxmoxv1alpha1.TaskObservation{UPID: upid, Operation: operation}
+}
+
+// pendingTask returns the operation of the task the VM is waiting for, if any.
+func pendingTask(vm *proxmoxv1alpha1.VirtualMachine) string {
+	if vm.Status.AtProvider.Task == nil {
+		return ""
+	}
+	return vm.Status.AtProvider.Task.Operation
+}
+
+// observeTask checks the task recorded in the VM status. It reports whether the
+// task is still running, and clears it from the status once it has finished.
+// A failed task is returned as an error carrying the tail of its log.
+func (e *external) observeTask(ctx context.Context, vm *proxmoxv1alpha1.VirtualMachine) (bool, error) {
+	task := vm.Status.AtProvider.Task
+	if task == nil {
+		return false, nil
+	}
+
+	status, err := e.client.GetTaskStatus(ctx, task.UPID)
+	if proxmoxclient.IsNotFound(err) {
+		// Task logs are lost when a node is reinstalled; nothing left to wait for.
+		e.log.Info("Proxmox task no longer exists", "UPID", task.UPID)
+		vm.Status.AtProvider.Task = nil
+		return false, nil
+	}
+	if err != nil {
+		return false, describeError(err, "get Proxmox task status")
+	}
+	if status.Running() {
+		e.log.V(1).Info("Waiting for Proxmox task", "UPID", task.UPID, "Operation", task.Operation)
+		return true, nil
+	}
+
+	vm.Status.AtProvider.Task = nil
+	if status.Succeeded() {
+		e.log.Info("Proxmox task finished", "UPID", task.UPID, "Operation", task.Operation, "ExitStatus", status.ExitStatus)
+		return false, nil
+	}
+
+	lines, logErr := e.client.GetTaskLog(ctx, task.UPID, taskLogLines)
+	if logErr != nil {
+		e.log.Error(logErr, "Cannot read Proxmox task log", "UPID", task.UPID)
+	}
+	return false, errors.Errorf("%s task %s failed: %s\n%s", task.Operation, task.UPID, status.ExitStatus, strings.Join(lines, "\n"))
+}
@@ -0,0 +1,79 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+
+	proxmoxv1alpha1 "provider-proxmox/api/v1alpha1"
+	"provider-proxmox/internal/proxmoxclient"
+)
+
+func TestObserveTask(t *testing.T) {
+	const upid = "UPID:pve:000F4240:0098967F:65A1B2C3:qmcreate:101:root@pam:"
+	// The task log has 12 lines, of which the last 10 are reported.
+	var tail []string
+	for n := 3; n <= 12; n++ {
+		tail = append(tail, "line "+strconv.Itoa(n))
+	}
+	tests := []struct {
+		name        string
+		status      interface{} // Data of the task status response
+		wantRunning bool
+		wantTask    bool   // Whether the task stays recorded
+		wantErr     string // Error message, empty for none
+	}{
+		{name: "running", status: map[string]string{"status": "running"}, wantRunning: true, wantTask: true},
+		{name: "succeeded", status: map[string]string{"status": "stopped", "exitstatus": "OK"}},
+		{name: "succeeded with warnings", status: map[string]string{"status": "stopped", "exitstatus": "WARNINGS: 2"}},
+		{
+			name:    "failed",
+			status:  map[string]string{"status": "stopped", "exitstatus": "unable to create VM 101"},
+			wantErr: "create task " + upid + " failed: unable to create VM 101\n" + strings.Join(tail, "\n"),
+		},
+		{name: "vanished", status: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if strings.HasSuffix(r.URL.Path, "/status") {
+					_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": tt.status})
+					return
+				}
+				var lines []map[string]interface{}
+				if r.URL.Query().Get("start") == "2" {
+					for i, line := range tail {
+						lines = append(lines, map[string]interface{}{"n": i + 3, "t": line})
+					}
+				}
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": lines, "total": 12})
+			}))
+			defer srv.Close()
+
+			e := &external{
+				client: &proxmoxclient.ProxmoxClient{Endpoint: srv.URL, APIToken: "root@pam!test=secret", HTTPClient: srv.Client(), Retry: proxmoxclient.RetryOptions{MaxAttempts: 1}},
+				log:    logr.Discard(),
+			}
+			vm := &proxmoxv1alpha1.VirtualMachine{}
+			trackTask(vm, upid, taskOpCreate)
+
+			running, err := e.observeTask(context.Background(), vm)
+			if running != tt.wantRunning {
+				t.Errorf("observeTask() running = %v, want %v", running, tt.wantRunning)
+			}
+			if (err != nil) != (tt.wantErr != "") || (err != nil && err.Error() != tt.wantErr) {
+				t.Errorf("observeTask() error = %v, want %q", err, tt.wantErr)
+			}
+			if got := vm.Status.AtProvider.Task != nil; got != tt.wantTask {
+				t.Errorf("observeTask() left task recorded = %v, want %v", got, tt.wantTask)
+			}
+		})
+	}
+}
@@ -0,0 +1,219 @@
+package controller
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+
+	proxmoxv1alpha1 "provider-proxmox/api/v1alpha1"
+	"provider-proxmox/internal/proxmoxclient"
+)
+
+// maxVMID is the largest VMID Proxmox accepts.
+const maxVMID = 999999999
+
+// vmidReservationTTL is how long an allocated VMID is withheld from other
+// VirtualMachines, covering the time until the VM holding it is created.
+const vmidReservationTTL = 5 * time.Minute
+
+// vmidReservations remembers the VMIDs recently allocated per ProviderConfig,
+// so that VirtualMachines created concurrently are not given the same VMID
+// before Proxmox reports it as used.
+type vmidReservations struct {
+	mu       sync.Mutex
+	reserved map[types.UID]map[int]time.Time
+	now      func() time.Time
+}
+
+func newVMIDReservations() *vmidReservations {
+	return &vmidReservations{reserved: map[types.UID]map[int]time.Time{}, now: time.Now}
+}
+
+// reserve reserves a VMID of a ProviderConfig, unless it is reserved already.
+func (r *vmidReservations) reserve(uid types.UID, vmid int) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	reserved := r.reserved[uid]
+	if reserved == nil {
+		reserved = map[int]time.Time{}
+		r.reserved[uid] = reserved
+	}
+	for id, expires := range reserved {
+		if now.After(expires) {
+			delete(reserved, id)
+		}
+	}
+	if _, ok := reserved[vmid]; ok {
+		return false
+	}
+	reserved[vmid] = now.Add(vmidReservationTTL)
+	return true
+}
+
+// release makes a reserved VMID available again.
+func (r *vmidReservations) release(uid types.UID, vmid int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.reserved[uid], vmid)
+}
+
+// externalName formats the external name of a VM: its node and VMID, e.g.
+// "pve/105", or only its VMID while the node is not known.
+func externalName(node string, vmid int) string {
+	if node == "" {
+		return strconv.Itoa(vmid)
+	}
+	return node + "/" + strconv.Itoa(vmid)
+}
+
+// parseExternalName parses an external name of the form node/vmid or vmid.
+func parseExternalName(name string) (string, int, bool) {
+	node, id, ok := strings.Cut(name, "/")
+	if !ok {
+		node, id = "", name
+	}
+	vmid, err := strconv.Atoi(id)
+	if err != nil || vmid <= 0 || (ok && node == "") {
+		return "", 0, false
+	}
+	return node, vmid, true
+}
+
+// externalVMID returns the VMID recorded in the external name of the VM.
+func externalVMID(vm *proxmoxv1alpha1.VirtualMachine) (int, bool) {
+	_, vmid, ok := parseExternalName(meta.GetExternalName(vm))
+	return vmid, ok
+}
+
+// externalNode returns the node recorded in the external name of the VM.
+func externalNode(vm *proxmoxv1alpha1.VirtualMachine) string {
+	node, _, _ := parseExternalName(meta.GetExternalName(vm))
+	return node
+}
+
+// ownsVM reports whether the VM with the VMID of the spec was created or
+// adopted by this VirtualMachine, rather than by someone else. The VMID is
+// recorded in the external name before the VM is created, and resources
+// created before VMIDs were recorded carry the create annotations instead.
+func ownsVM(vm *proxmoxv1alpha1.VirtualMachine) bool {
+	if vmid, ok := externalVMID(vm); ok {
+		return vmid == vm.Spec.VMID
+	}
+	return !meta.GetExternalCreateSucceeded(vm).IsZero()
+}
+
+// lateInitVMID takes the VMID of the spec from the external name, which
+// adopts an existing VM. It reports whether the spec changed.
+func lateInitVMID(vm *proxmoxv1alpha1.VirtualMachine) (bool, error) {
+	vmid, ok := externalVMID(vm)
+	switch {
+	case !ok:
+		return false, nil
+	case vm.Spec.VMID == 0:
+		vm.Spec.VMID = vmid
+		return true, nil
+	case vm.Spec.VMID != vmid:
+		return false, errors.Errorf("spec.vmid %d does not match the VMID %d of the %s annotation", vm.Spec.VMID, vmid, meta.AnnotationKeyExternalName)
+	}
+	return false, nil
+}
+
+// vmidConflict describes a VMID of the spec taken by a VM this VirtualMachine
+// does not own.
+func vmidConflict(vm *proxmoxv1alpha1.VirtualMachine, node string) error {
+	return errors.Errorf("VMID %d is already used by another VM on node %s; remove spec.vmid to allocate a free VMID, "+
+		"or set the %s annotation to %s to manage the existing VM", vm.Spec.VMID, node, meta.AnnotationKeyExternalName, externalName(node, vm.Spec.VMID))
+}
+
+// vmidRange returns the range VMIDs are allocated from: the range of the
+// ProviderConfig, else from the next free VMID of the cluster.
+func (e *external) vmidRange(ctx context.Context) (int, int, error) {
+	if r := e.pc.Spec.VMIDRange; r != nil {
+		return r.Min, r.Max, nil
+	}
+	next, err := e.client.NextVMID(ctx)
+	if err != nil {
+		return 0, 0, describeError(err, "get next free VMID")
+	}
+	return next, maxVMID, nil
+}
+
+// allocateVMID reserves a VMID no VM of the cluster uses. Proxmox confirms
+// each candidate, as the cluster resources may lag behind.
+func (e *external) allocateVMID(ctx context.Context) (int, error) {
+	lo, hi, err := e.vmidRange(ctx)
+	if err != nil {
+		return 0, err
+	}
+	resources, err := e.client.ClusterResources(ctx, "vm")
+	if err != nil {
+		return 0, describeError(err, "list cluster VMs")
+	}
+	used := make(map[int]bool, len(resources))
+	for _, r := range resources {
+		used[r.VMID] = true
+	}
+
+	for vmid := lo; vmid <= hi; vmid++ {
+		if used[vmid] || !e.vmids.reserve(e.pc.UID, vmid) {
+			continue
+		}
+		err := e.client.CheckVMID(ctx, vmid)
+		if err == nil {
+			return vmid, nil
+		}
+		e.vmids.release(e.pc.UID, vmid)
+		if !proxmoxclient.IsConflict(err) {
+			return 0, describeError(err, "check VMID")
+		}
+	}
+	return 0, errors.Errorf("no free VMID in range %d-%d", lo, hi)
+}
+
+// recordVMID sets the VMID of the VM, allocating one if the spec has none,
+// and persists it in the spec and the external name, along with the node,
+// before the VM is created, so that a retried create uses the same VMID. The
+// spec is persisted as well when indexed reports that the caller set device
+// indexes. It reports whether the VMID was allocated.
+func (e *external) recordVMID(ctx context.Context, vm *proxmoxv1alpha1.VirtualMachine, node string, indexed bool) (bool, error) {
+	allocated := false
+	if vm.Spec.VMID == 0 {
+		vmid, err := e.allocateVMID(ctx)
+		if err != nil {
+			return false, err
+		}
+		e.log.Info("Allocated VMID", "VMID", vmid)
+		vm.Spec.VMID, allocated = vmid, true
+	}
+	if vmid, ok := externalVMID(vm); ok && vmid == vm.Spec.VMID && !indexed {
+		return allocated, nil
+	}
+	meta.SetExternalName(vm, externalName(node, vm.Spec.VMID))
+	if err := e.kube.Update(ctx, vm); err != nil {
+		if allocated {
+			e.vmids.release(e.pc.UID, vm.Spec.VMID)
+		}
+		forgetVMID(vm, allocated)
+		return false, errors.Wrap(err, "cannot record VMID")
+	}
+	return allocated, nil
+}
+
+// forgetVMID drops a VMID Proxmox refused because another VM took it in the
+// meantime, so that the next attempt does not manage that VM. An allocated
+// VMID is dropped from the spec as well, to allocate a new one.
+func forgetVMID(vm *proxmoxv1alpha1.VirtualMachine, allocated bool) {
+	meta.SetExternalName(vm, "")
+	if allocated {
+		vm.Spec.VMID = 0
+	}
+}
@@ -0,0 +1,133 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+
+	proxmoxv1alpha1 "provider-proxmox/api/v1alpha1"
+)
+
+func TestVMIDReservations(t *testing.T) {
+	now := time.Now()
+	r := newVMIDReservations()
+	r.now = func() time.Time { return now }
+
+	if !r.reserve("uid-a", 100) {
+		t.Fatal("reserve() = false for a free VMID")
+	}
+	if r.reserve("uid-a", 100) {
+		t.Error("reserve() = true for a reserved VMID")
+	}
+	if !r.reserve("uid-b", 100) {
+		t.Error("reserve() = false for a VMID reserved by another ProviderConfig")
+	}
+
+	r.release("uid-a", 100)
+	if !r.reserve("uid-a", 100) {
+		t.Error("reserve() = false for a released VMID")
+	}
+
+	now = now.Add(vmidReservationTTL + time.Second)
+	if !r.reserve("uid-b", 100) {
+		t.Error("reserve() = false for an expired reservation")
+	}
+}
+
+func TestParseExternalName(t *testing.T) {
+	tests := []struct {
+		name     string
+		wantNode string
+		wantVMID int
+		wantOK   bool
+	}{
+		{name: "105", wantVMID: 105, wantOK: true},
+		{name: "pve/105", wantNode: "pve", wantVMID: 105, wantOK: true},
+		{name: ""},
+		{name: "test"},
+		{name: "/105"},
+		{name: "pve/"},
+		{name: "pve/-1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node, vmid, ok := parseExternalName(tt.name)
+			if node != tt.wantNode || vmid != tt.wantVMID || ok != tt.wantOK {
+				t.Errorf("parseExternalName(%q) = %q, %d, %v, want %q, %d, %v", tt.name, node, vmid, ok, tt.wantNode, tt.wantVMID, tt.wantOK)
+			}
+		})
+	}
+	if got := externalName("pve", 105); got != "pve/105" {
+		t.Errorf("externalName() = %q, want pve/105", got)
+	}
+}
+
+func TestLateInitVMID(t *testing.T) {
+	tests := []struct {
+		name         string
+		vmid         int
+		externalName string
+		want         int
+		wantChanged  bool
+		wantErr      bool
+	}{
+		{name: "no external name"},
+		{name: "name of the resource", externalName: "test"},
+		{name: "adopted", externalName: "105", want: 105, wantChanged: true},
+		{name: "adopted with node", externalName: "pve/105", want: 105, wantChanged: true},
+		{name: "matching", vmid: 105, externalName: "105", want: 105},
+		{name: "spec only", vmid: 105, want: 105},
+		{name: "mismatch", vmid: 105, externalName: "106", want: 105, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vm := &proxmoxv1alpha1.VirtualMachine{Spec: proxmoxv1alpha1.VirtualMachineSpec{VMID: tt.vmid}}
+			if tt.externalName != "" {
+				meta.SetExternalName(vm, tt.externalName)
+			}
+			changed, err := lateInitVMID(vm)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("lateInitVMID() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if changed != tt.wantChanged || vm.Spec.VMID != tt.want {
+				t.Errorf("lateInitVMID() = %v, VMID %d, want %v, VMID %d", changed, vm.Spec.VMID, tt.wantChanged, tt.want)
+			}
+		})
+	}
+}
+
+func TestOwnsVM(t *testing.T) {
+	vm := func(externalName string, created bool) *proxmoxv1alpha1.VirtualMachine {
+		vm := &proxmoxv1alpha1.VirtualMachine{Spec: proxmoxv1alpha1.VirtualMachineSpec{VMID: 105}}
+		if externalName != "" {
+			meta.SetExternalName(vm, externalName)
+		}
+		if created {
+			meta.SetExternalCreateSucceeded(vm, time.Now())
+		}
+		return vm
+	}
+
+	tests := []struct {
+		name string
+		vm   *proxmoxv1alpha1.VirtualMachine
+		want bool
+	}{
+		{name: "recorded VMID", vm: vm("105", false), want: true},
+		{name: "recorded node and VMID", vm: vm("pve/105", false), want: true},
+		{name: "created before VMIDs were recorded", vm: vm("test", true), want: true},
+		{name: "foreign VM", vm: vm("", false)},
+		{name: "other VMID", vm: vm("106", true)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ownsVM(tt.vm); got != tt.want {
+				t.Errorf("ownsVM() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
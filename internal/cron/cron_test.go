@@ -0,0 +1,74 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	for _, expr := range []string{"* * * * *", "0 2 * * 6", "*/15 1-5 1,15 * 1-5", "30 3 * 1-3/2 7", "0-30/10 * * * *"} {
+		if _, err := Parse(expr); err != nil {
+			t.Errorf("Parse(%q) error = %v", expr, err)
+		}
+	}
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "5-1 * * * *", "*/0 * * * *", "a * * * *", "* * * * 8"} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Parse(%q) error = nil, want error", expr)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	at := func(s string) time.Time {
+		v, err := time.Parse("2006-01-02 15:04", s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	tests := []struct {
+		expr  string
+		after string
+		want  string
+	}{
+		{expr: "* * * * *", after: "2024-05-01 10:00", want: "2024-05-01 10:01"},
+		{expr: "0 2 * * 6", after: "2024-05-01 10:00", want: "2024-05-04 02:00"},
+		{expr: "0 2 * * 6", after: "2024-05-04 02:00", want: "2024-05-11 02:00"},
+		{expr: "*/15 * * * *", after: "2024-05-01 10:07", want: "2024-05-01 10:15"},
+		{expr: "30 23 31 12 *", after: "2024-05-01 10:00", want: "2024-12-31 23:30"},
+		{expr: "0 0 29 2 *", after: "2025-03-01 00:00", want: "2028-02-29 00:00"},
+		// Either the day of month or the day of week matches.
+		{expr: "0 0 15 * 1", after: "2024-05-07 00:00", want: "2024-05-13 00:00"},
+		{expr: "0 0 * * 7", after: "2024-05-01 00:00", want: "2024-05-05 00:00"},
+	}
+	for _, tt := range tests {
+		s, err := Parse(tt.expr)
+		if err != nil {
+			t.Fatalf("Parse(%q) error = %v", tt.expr, err)
+		}
+		if got := s.Next(at(tt.after)); !got.Equal(at(tt.want)) {
+			t.Errorf("Parse(%q).Next(%s) = %s, want %s", tt.expr, tt.after, got, tt.want)
+		}
+	}
+
+	s, _ := Parse("0 0 30 2 *")
+	if got := s.Next(at("2024-05-01 00:00")); !got.IsZero() {
+		t.Errorf("Next() = %s for February 30, want zero time", got)
+	}
+}
+
+func TestActive(t *testing.T) {
+	s, _ := Parse("0 2 * * 6") // Saturdays at 2:00
+	for at, want := range map[string]bool{
+		"2024-05-04 01:59": false,
+		"2024-05-04 02:00": true,
+		"2024-05-04 02:59": true,
+		"2024-05-04 03:00": false,
+		"2024-05-05 02:30": false,
+	} {
+		v, _ := time.Parse("2006-01-02 15:04", at)
+		if got := s.Active(v, time.Hour); got != want {
+			t.Errorf("Active(%s) = %v, want %v", at, got, want)
+		}
+	}
+}
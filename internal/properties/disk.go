@@ -0,0 +1,144 @@
+package properties
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// allocationRe matches a volume that asks Proxmox to allocate a new disk of
+// the given size in GiB, e.g. "local-lvm:32".
+var allocationRe = regexp.MustCompile(`^([^:]+):(\d+(?:\.\d+)?)$`)
+
+// Disk is a scsiN, virtioN, sataN or ideN option, e.g.
+// "local-lvm:vm-101-disk-0,iothread=1,size=32G".
+type Disk struct {
+	File     string // Volume, e.g. "local-lvm:vm-101-disk-0", "local:iso/debian.iso", "none" or "cdrom"
+	Media    string // disk or cdrom
+	Size     string // Size reported by Proxmox, e.g. "32G"
+	Format   string // raw, qcow2 or vmdk
+	Cache    string // none, writethrough, writeback, unsafe or directsync
+	IOThread *bool
+	Discard  *bool // Printed as on or ignore
+	SSD      *bool
+	Backup   *bool
+
+	props List // Options as parsed, including those not modeled above
+}
+
+// NewAllocation returns a disk that allocates a new volume of sizeGiB on a storage.
+func NewAllocation(storage string, sizeGiB string) *Disk {
+	return &Disk{File: storage + ":" + sizeGiB}
+}
+
+// ParseDisk parses a disk option.
+func ParseDisk(s string) (*Disk, error) {
+	props := Parse(s)
+	if len(props) == 0 {
+		return nil, fmt.Errorf("empty disk")
+	}
+
+	d := &Disk{props: props, File: props.Head()}
+	d.Media = props.Value("media")
+	d.Size = props.Value("size")
+	d.Format = props.Value("format")
+	d.Cache = props.Value("cache")
+	d.IOThread = parseBoolOption(props, "iothread")
+	d.SSD = parseBoolOption(props, "ssd")
+	d.Backup = parseBoolOption(props, "backup")
+	if v, ok := props.Get("discard"); ok {
+		on := v == "on"
+		d.Discard = &on
+	}
+	return d, nil
+}
+
+// Storage returns the storage holding the volume, empty for "none" and "cdrom".
+func (d *Disk) Storage() string {
+	storage, _, ok := strings.Cut(d.File, ":")
+	if !ok {
+		return ""
+	}
+	return storage
+}
+
+// Allocation returns the storage and size in GiB when the disk asks Proxmox
+// to allocate a new volume.
+func (d *Disk) Allocation() (storage, sizeGiB string, ok bool) {
+	m := allocationRe.FindStringSubmatch(d.File)
+	if m == nil {
+		return "", "", false
+	}
+	return m[1], m[2], true
+}
+
+// IsCDROM reports whether the disk is a CD-ROM drive.
+func (d *Disk) IsCDROM() bool {
+	return d.Media == "cdrom" || d.File == "cdrom"
+}
+
+// Options returns the options of the disk, with the typed fields applied.
+func (d *Disk) Options() List {
+	props := d.props.Clone()
+	switch {
+	case len(props) == 0:
+		props = List{{Key: d.File}}
+	case props[0].Key == "file" || props[0].Key == "volume":
+		props[0].Value = d.File
+	case props.Head() != d.File:
+		props[0] = Property{Key: d.File}
+	}
+
+	props = props.set("media", d.Media)
+	props = props.setBool("iothread", d.IOThread)
+	props = props.setBool("ssd", d.SSD)
+	props = props.setBool("backup", d.Backup)
+	props = props.set("cache", d.Cache)
+	props = props.set("format", d.Format)
+	props = props.set("discard", formatDiscard(d.Discard))
+	props = props.set("size", d.Size)
+	return props
+}
+
+// String formats the disk as an option.
+func (d *Disk) String() string {
+	return d.Options().String()
+}
+
+func formatDiscard(discard *bool) string {
+	switch {
+	case discard == nil:
+		return ""
+	case *discard:
+		return "on"
+	default:
+		return "ignore"
+	}
+}
+
+// SizeMiB converts a Proxmox size such as "32G" or "512M" to MiB. A missing
+// unit means GiB, as in allocations. It returns -1 for invalid sizes.
+func SizeMiB(s string) int64 {
+	if s == "" {
+		return 0
+	}
+	mult := float64(1024)
+	switch s[len(s)-1] {
+	case 'K', 'k':
+		mult = 1.0 / 1024
+	case 'M', 'm':
+		mult = 1
+	case 'G', 'g':
+		mult = 1024
+	case 'T', 't':
+		mult = 1024 * 1024
+	default:
+		s += "G"
+	}
+	n, err := strconv.ParseFloat(s[:len(s)-1], 64)
+	if err != nil {
+		return -1
+	}
+	return int64(n * mult)
+}
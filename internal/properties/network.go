@@ -0,0 +1,93 @@
+package properties
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NetworkModels are the NIC models Proxmox emulates.
+var NetworkModels = []string{
+	"e1000", "e1000-82540em", "e1000-82544gc", "e1000-82545em", "e1000e", "i82551", "i82557b",
+	"i82559er", "ne2k_isa", "ne2k_pci", "pcnet", "rtl8139", "virtio", "vmxnet3",
+}
+
+func isNetworkModel(s string) bool {
+	for _, m := range NetworkModels {
+		if m == s {
+			return true
+		}
+	}
+	return false
+}
+
+// NetworkDevice is a netN option, e.g. "virtio=BC:24:11:2E:4F:01,bridge=vmbr0,tag=20".
+type NetworkDevice struct {
+	Model    string // NIC model, e.g. virtio
+	MAC      string // Generated by Proxmox when empty
+	Bridge   string
+	Tag      int // VLAN tag, 0 when untagged
+	Firewall *bool
+	LinkDown *bool
+	Rate     string // Rate limit in MB/s
+	MTU      int
+	Queues   int
+
+	props List // Options as parsed, including those not modeled above
+}
+
+// ParseNetworkDevice parses a netN option.
+func ParseNetworkDevice(s string) (*NetworkDevice, error) {
+	props := Parse(s)
+	if len(props) == 0 {
+		return nil, fmt.Errorf("empty network device")
+	}
+
+	n := &NetworkDevice{props: props}
+	if head := props[0]; isNetworkModel(head.Key) {
+		n.Model, n.MAC = head.Key, head.Value
+	} else {
+		n.Model, n.MAC = props.Value("model"), props.Value("macaddr")
+	}
+	if n.Model == "" {
+		return nil, fmt.Errorf("network device %q has no model", s)
+	}
+	n.Bridge = props.Value("bridge")
+	n.Tag = parseIntOption(props, "tag")
+	n.Firewall = parseBoolOption(props, "firewall")
+	n.LinkDown = parseBoolOption(props, "link_down")
+	n.Rate = props.Value("rate")
+	n.MTU = parseIntOption(props, "mtu")
+	n.Queues = parseIntOption(props, "queues")
+	return n, nil
+}
+
+// Options returns the options of the device, with the typed fields applied.
+func (n *NetworkDevice) Options() List {
+	props := n.props.Clone()
+	if len(props) > 0 && isNetworkModel(props[0].Key) {
+		props[0] = Property{Key: n.Model, Value: n.MAC}
+	} else if len(props) > 0 && (props.Value("model") != "" || props.Value("macaddr") != "") {
+		props = props.set("model", n.Model).set("macaddr", n.MAC)
+	} else {
+		props = append(List{{Key: n.Model, Value: n.MAC}}, props...)
+	}
+
+	props = props.set("bridge", n.Bridge)
+	props = props.setInt("tag", n.Tag)
+	props = props.setBool("firewall", n.Firewall)
+	props = props.setBool("link_down", n.LinkDown)
+	props = props.set("rate", n.Rate)
+	props = props.setInt("mtu", n.MTU)
+	props = props.setInt("queues", n.Queues)
+	return props
+}
+
+// String formats the device as a netN option.
+func (n *NetworkDevice) String() string {
+	return n.Options().String()
+}
+
+// SameMAC reports whether two MAC addresses are equal, ignoring case.
+func SameMAC(a, b string) bool {
+	return strings.EqualFold(a, b)
+}
@@ -0,0 +1,185 @@
+// Package properties parses and formats the property strings Proxmox uses for
+// device options, such as "virtio=BC:24:11:2E:4F:01,bridge=vmbr0,firewall=1".
+//
+// Parsing a string and formatting it again returns it unchanged. Typed devices
+// keep the options they do not model, and the order of all options, so that
+// changing a single option leaves the rest of the string untouched.
+package properties
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Property is a single key[=value] element of a property string. An element
+// without "=" has an empty value, e.g. "virtio" in "virtio,bridge=vmbr0".
+type Property struct {
+	Key   string
+	Value string
+}
+
+// List is a property string split into its elements, in their original order.
+type List []Property
+
+// Parse splits a property string into its elements.
+func Parse(s string) List {
+	if s == "" {
+		return nil
+	}
+	parts := strings.Split(s, ",")
+	l := make(List, 0, len(parts))
+	for _, part := range parts {
+		k, v, _ := strings.Cut(part, "=")
+		l = append(l, Property{Key: k, Value: v})
+	}
+	return l
+}
+
+// String formats the list as a property string.
+func (l List) String() string {
+	parts := make([]string, 0, len(l))
+	for _, p := range l {
+		if p.Value == "" {
+			parts = append(parts, p.Key)
+			continue
+		}
+		parts = append(parts, p.Key+"="+p.Value)
+	}
+	return strings.Join(parts, ",")
+}
+
+// Get returns the value of an option.
+func (l List) Get(key string) (string, bool) {
+	for _, p := range l {
+		if p.Key == key {
+			return p.Value, true
+		}
+	}
+	return "", false
+}
+
+// Value returns the value of an option, or an empty string if it is absent.
+func (l List) Value(key string) string {
+	v, _ := l.Get(key)
+	return v
+}
+
+// Set changes the value of an option in place, or appends it if absent.
+func (l List) Set(key, value string) List {
+	for i := range l {
+		if l[i].Key == key {
+			l[i].Value = value
+			return l
+		}
+	}
+	return append(l, Property{Key: key, Value: value})
+}
+
+// Delete removes an option.
+func (l List) Delete(key string) List {
+	out := l[:0]
+	for _, p := range l {
+		if p.Key != key {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// Clone returns a copy of the list that can be changed independently.
+func (l List) Clone() List {
+	if l == nil {
+		return nil
+	}
+	return append(List(nil), l...)
+}
+
+// Head returns the leading value of a device, which Proxmox prints without its
+// key: the volume of a disk ("file" or "volume") or the model of a NIC.
+func (l List) Head() string {
+	if len(l) == 0 {
+		return ""
+	}
+	switch p := l[0]; {
+	case p.Key == "file" || p.Key == "volume":
+		return p.Value
+	case p.Value == "":
+		return p.Key
+	default:
+		return p.Key + "=" + p.Value
+	}
+}
+
+// set, setBool and setInt update the option of a typed field, removing it for
+// a zero value. An option already holding the value is left exactly as it was
+// written, e.g. "firewall=on" is not rewritten to "firewall=1".
+
+func (l List) set(key, value string) List {
+	if l.Value(key) == value {
+		return l
+	}
+	if value == "" {
+		return l.Delete(key)
+	}
+	return l.Set(key, value)
+}
+
+func (l List) setBool(key string, value *bool) List {
+	current := parseBoolOption(l, key)
+	switch {
+	case value == nil && current == nil:
+		return l
+	case value == nil:
+		return l.Delete(key)
+	case current != nil && *current == *value:
+		return l
+	}
+	return l.Set(key, FormatBool(*value))
+}
+
+func (l List) setInt(key string, value int) List {
+	if parseIntOption(l, key) == value {
+		return l
+	}
+	if value == 0 {
+		return l.Delete(key)
+	}
+	return l.Set(key, strconv.Itoa(value))
+}
+
+// ParseBool interprets a Proxmox boolean option, which is printed as 0 or 1
+// but also accepted as on/off, yes/no and true/false.
+func ParseBool(s string) (bool, bool) {
+	switch strings.ToLower(s) {
+	case "1", "on", "yes", "true":
+		return true, true
+	case "0", "off", "no", "false":
+		return false, true
+	}
+	return false, false
+}
+
+// FormatBool renders a boolean option as Proxmox prints it.
+func FormatBool(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
+
+func parseBoolOption(l List, key string) *bool {
+	v, ok := l.Get(key)
+	if !ok {
+		return nil
+	}
+	b, ok := ParseBool(v)
+	if !ok {
+		return nil
+	}
+	return &b
+}
+
+func parseIntOption(l List, key string) int {
+	n, _ := strconv.Atoi(l.Value(key))
+	return n
+}
@@ -0,0 +1,169 @@
+package properties
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRoundTrip(t *testing.T) {
+	nets := []string{
+		"virtio=BC:24:11:2E:4F:01,bridge=vmbr0",
+		"virtio=BC:24:11:2E:4F:01,bridge=vmbr0,firewall=1,tag=20",
+		"e1000=BC:24:11:2E:4F:02,bridge=vmbr1,link_down=1,mtu=1500,queues=4,rate=12.5",
+		"model=virtio,macaddr=BC:24:11:2E:4F:03,bridge=vmbr0",
+		"virtio,bridge=vmbr0,firewall=on,trunks=10;20",
+	}
+	for _, s := range nets {
+		n, err := ParseNetworkDevice(s)
+		if err != nil {
+			t.Errorf("ParseNetworkDevice(%q) error = %v", s, err)
+			continue
+		}
+		if got := n.String(); got != s {
+			t.Errorf("ParseNetworkDevice(%q).String() = %q", s, got)
+		}
+	}
+
+	disks := []string{
+		"local-lvm:vm-101-disk-0,size=32G",
+		"local-lvm:vm-101-disk-0,cache=writeback,discard=on,iothread=1,size=32G,ssd=1",
+		"file=local-lvm:vm-101-disk-0,backup=0,size=512M",
+		"local:iso/debian-12.5.0-amd64-netinst.iso,media=cdrom,size=629M",
+		"none,media=cdrom",
+		"local-lvm:32,iothread=0",
+		"ceph:vm-101-disk-1,aio=native,replicate=0,size=1T",
+	}
+	for _, s := range disks {
+		d, err := ParseDisk(s)
+		if err != nil {
+			t.Errorf("ParseDisk(%q) error = %v", s, err)
+			continue
+		}
+		if got := d.String(); got != s {
+			t.Errorf("ParseDisk(%q).String() = %q", s, got)
+		}
+	}
+}
+
+func TestParseNetworkDevice(t *testing.T) {
+	n, err := ParseNetworkDevice("virtio=BC:24:11:2E:4F:01,bridge=vmbr0,firewall=1,tag=20,mtu=1")
+	if err != nil {
+		t.Fatalf("ParseNetworkDevice() error = %v", err)
+	}
+	yes := true
+	want := &NetworkDevice{Model: "virtio", MAC: "BC:24:11:2E:4F:01", Bridge: "vmbr0", Tag: 20, Firewall: &yes, MTU: 1, props: n.props}
+	if !reflect.DeepEqual(n, want) {
+		t.Errorf("ParseNetworkDevice() = %+v, want %+v", n, want)
+	}
+
+	for _, s := range []string{"", "bridge=vmbr0"} {
+		if _, err := ParseNetworkDevice(s); err == nil {
+			t.Errorf("ParseNetworkDevice(%q) error = nil, want error", s)
+		}
+	}
+}
+
+func TestNetworkDeviceChanges(t *testing.T) {
+	no := false
+	tests := []struct {
+		name   string
+		in     string
+		change func(*NetworkDevice)
+		want   string
+	}{
+		{name: "new device", change: func(n *NetworkDevice) { n.Model, n.Bridge, n.Tag = "virtio", "vmbr0", 20 }, want: "virtio,bridge=vmbr0,tag=20"},
+		{name: "bridge keeps other options", in: "virtio=BC:24:11:2E:4F:01,bridge=vmbr0,firewall=on,trunks=10;20", change: func(n *NetworkDevice) { n.Bridge = "vmbr1" }, want: "virtio=BC:24:11:2E:4F:01,bridge=vmbr1,firewall=on,trunks=10;20"},
+		{name: "disable firewall", in: "virtio=BC:24:11:2E:4F:01,bridge=vmbr0,firewall=on", change: func(n *NetworkDevice) { n.Firewall = &no }, want: "virtio=BC:24:11:2E:4F:01,bridge=vmbr0,firewall=0"},
+		{name: "remove tag", in: "virtio=BC:24:11:2E:4F:01,bridge=vmbr0,tag=20,queues=2", change: func(n *NetworkDevice) { n.Tag = 0 }, want: "virtio=BC:24:11:2E:4F:01,bridge=vmbr0,queues=2"},
+		{name: "change model", in: "e1000=BC:24:11:2E:4F:01,bridge=vmbr0", change: func(n *NetworkDevice) { n.Model = "virtio" }, want: "virtio=BC:24:11:2E:4F:01,bridge=vmbr0"},
+		{name: "explicit model key", in: "model=virtio,bridge=vmbr0", change: func(n *NetworkDevice) { n.MAC = "BC:24:11:2E:4F:01" }, want: "model=virtio,bridge=vmbr0,macaddr=BC:24:11:2E:4F:01"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := &NetworkDevice{}
+			if tt.in != "" {
+				var err error
+				if n, err = ParseNetworkDevice(tt.in); err != nil {
+					t.Fatalf("ParseNetworkDevice(%q) error = %v", tt.in, err)
+				}
+			}
+			tt.change(n)
+			if got := n.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDisk(t *testing.T) {
+	d, err := ParseDisk("file=local-lvm:vm-101-disk-0,discard=on,iothread=1,size=32G")
+	if err != nil {
+		t.Fatalf("ParseDisk() error = %v", err)
+	}
+	if d.File != "local-lvm:vm-101-disk-0" || d.Size != "32G" || d.Discard == nil || !*d.Discard || d.IOThread == nil || !*d.IOThread {
+		t.Errorf("ParseDisk() = %+v", d)
+	}
+	if got := d.Storage(); got != "local-lvm" {
+		t.Errorf("Storage() = %q, want %q", got, "local-lvm")
+	}
+	if _, _, ok := d.Allocation(); ok {
+		t.Errorf("Allocation() ok = true for an existing volume")
+	}
+
+	d, _ = ParseDisk("local-lvm:0.5,ssd=1")
+	if storage, size, ok := d.Allocation(); !ok || storage != "local-lvm" || size != "0.5" {
+		t.Errorf("Allocation() = %q, %q, %v, want %q, %q, true", storage, size, ok, "local-lvm", "0.5")
+	}
+
+	d, _ = ParseDisk("none,media=cdrom")
+	if !d.IsCDROM() || d.Storage() != "" {
+		t.Errorf("ParseDisk(%q) = %+v, want a CD-ROM without storage", "none,media=cdrom", d)
+	}
+
+	if _, err := ParseDisk(""); err == nil {
+		t.Errorf("ParseDisk(%q) error = nil, want error", "")
+	}
+}
+
+func TestDiskChanges(t *testing.T) {
+	yes, no := true, false
+	tests := []struct {
+		name   string
+		in     string
+		change func(*Disk)
+		want   string
+	}{
+		{name: "new allocation", change: func(d *Disk) { d.File, d.IOThread = "local-lvm:32", &yes }, want: "local-lvm:32,iothread=1"},
+		{name: "enable discard", in: "local-lvm:vm-101-disk-0,size=32G", change: func(d *Disk) { d.Discard = &yes }, want: "local-lvm:vm-101-disk-0,size=32G,discard=on"},
+		{name: "disable discard", in: "local-lvm:vm-101-disk-0,discard=on,size=32G", change: func(d *Disk) { d.Discard = &no }, want: "local-lvm:vm-101-disk-0,discard=ignore,size=32G"},
+		{name: "replace volume", in: "local-lvm:32,iothread=1", change: func(d *Disk) { d.File, d.Size = "local-lvm:vm-101-disk-0", "32G" }, want: "local-lvm:vm-101-disk-0,iothread=1,size=32G"},
+		{name: "file key kept", in: "file=local:iso/a.iso,media=cdrom", change: func(d *Disk) { d.File = "local:iso/b.iso" }, want: "file=local:iso/b.iso,media=cdrom"},
+		{name: "unknown options kept", in: "ceph:vm-101-disk-1,aio=native,backup=0,size=1T", change: func(d *Disk) { d.Backup = nil }, want: "ceph:vm-101-disk-1,aio=native,size=1T"},
+		{name: "same value keeps spelling", in: "local-lvm:vm-101-disk-0,ssd=on", change: func(d *Disk) { d.SSD = &yes }, want: "local-lvm:vm-101-disk-0,ssd=on"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &Disk{}
+			if tt.in != "" {
+				var err error
+				if d, err = ParseDisk(tt.in); err != nil {
+					t.Fatalf("ParseDisk(%q) error = %v", tt.in, err)
+				}
+			}
+			tt.change(d)
+			if got := d.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSizeMiB(t *testing.T) {
+	for in, want := range map[string]int64{"": 0, "32": 32768, "32G": 32768, "512M": 512, "1T": 1048576, "2048K": 2, "1.5G": 1536, "x": -1} {
+		if got := SizeMiB(in); got != want {
+			t.Errorf("SizeMiB(%q) = %d, want %d", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package proxmoxclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// GuestInterface is a network interface reported by the QEMU guest agent.
+type GuestInterface struct {
+	Name        string           `json:"name"`
+	MACAddress  string           `json:"hardware-address"`
+	IPAddresses []GuestIPAddress `json:"ip-addresses"`
+}
+
+// GuestIPAddress is an address of a guest network interface.
+type GuestIPAddress struct {
+	Address string `json:"ip-address"`
+	Type    string `json:"ip-address-type"` // ipv4 or ipv6
+	Prefix  int    `json:"prefix"`
+}
+
+// GuestNetworkInterfaces lists the network interfaces of a running VM as
+// reported by its guest agent.
+func (c *ProxmoxClient) GuestNetworkInterfaces(ctx context.Context, node string, vmid int) ([]GuestInterface, error) {
+	var result []GuestInterface
+	if err := c.agentRequest(ctx, node, vmid, "network-get-interfaces", &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GuestHostname returns the host name of a running VM as reported by its guest agent.
+func (c *ProxmoxClient) GuestHostname(ctx context.Context, node string, vmid int) (string, error) {
+	var result struct {
+		HostName string `json:"host-name"`
+	}
+	if err := c.agentRequest(ctx, node, vmid, "get-host-name", &result); err != nil {
+		return "", err
+	}
+	return result.HostName, nil
+}
+
+// agentRequest runs a read-only guest agent command and decodes its result.
+func (c *ProxmoxClient) agentRequest(ctx context.Context, node string, vmid int, command string, result interface{}) error {
+	resp, err := c.Request(ctx, "GET", fmt.Sprintf("/api2/json/nodes/%s/qemu/%d/agent/%s", node, vmid, command), nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var agentResponse struct {
+		Data struct {
+			Result json.RawMessage `json:"result"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&agentResponse); err != nil {
+		return fmt.Errorf("failed to parse guest agent %s response: %w", command, err)
+	}
+	if len(agentResponse.Data.Result) == 0 {
+		return fmt.Errorf("guest agent %s returned no result", command)
+	}
+	if err := json.Unmarshal(agentResponse.Data.Result, result); err != nil {
+		return fmt.Errorf("failed to parse guest agent %s result: %w", command, err)
+	}
+	return nil
+}
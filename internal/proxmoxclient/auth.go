@@ -0,0 +1,96 @@
+package proxmoxclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Proxmox tickets are valid for two hours. They are renewed well before that,
+// using the current ticket in place of the password as the Proxmox UI does.
+const ticketRenewAfter = time.Hour
+
+// login requests a new ticket from /access/ticket. secret is either the
+// password or a still valid ticket to renew. The caller must hold c.mu unless
+// the client is not shared yet.
+func (c *ProxmoxClient) login(ctx context.Context, secret string) error {
+	authURL := fmt.Sprintf("%s/api2/json/access/ticket", c.ActiveEndpoint())
+	authPayload := url.Values{"username": {c.username}, "password": {secret}}
+
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "POST", authURL, strings.NewReader(authPayload.Encode()))
+	if err != nil {
+		return fmt.Errorf("failed to create auth request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to authenticate with Proxmox API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return newAPIError(req.Method, "/api2/json/access/ticket", resp)
+	}
+
+	var authResponse struct {
+		Data struct {
+			Ticket              string `json:"ticket"`
+			CSRFPreventionToken string `json:"CSRFPreventionToken"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&authResponse); err != nil {
+		return fmt.Errorf("failed to parse authentication response: %w", err)
+	}
+	if authResponse.Data.Ticket == "" {
+		return errors.New("authentication with Proxmox API returned no ticket")
+	}
+
+	c.ticket = authResponse.Data.Ticket
+	c.csrfToken = authResponse.Data.CSRFPreventionToken
+	c.issued = time.Now()
+	return nil
+}
+
+// credentials returns the ticket and CSRF token to use for a request,
+// renewing the ticket first when it is getting old.
+func (c *ProxmoxClient) credentials(ctx context.Context) (string, string, error) {
+	if c.APIToken != "" {
+		return "", "", nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if time.Since(c.issued) >= ticketRenewAfter {
+		if err := c.login(ctx, c.ticket); err != nil {
+			// The ticket may have expired already; fall back to the password.
+			if err := c.login(ctx, c.password); err != nil {
+				return "", "", err
+			}
+		}
+	}
+	return c.ticket, c.csrfToken, nil
+}
+
+// relogin obtains a new ticket with the password after a request using
+// failedTicket was rejected. Concurrent callers that failed with the same
+// ticket share a single login.
+func (c *ProxmoxClient) relogin(ctx context.Context, failedTicket string) (string, string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.ticket == failedTicket {
+		if err := c.login(ctx, c.password); err != nil {
+			return "", "", err
+		}
+	}
+	return c.ticket, c.csrfToken, nil
+}
@@ -0,0 +1,192 @@
+package proxmoxclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// ticketServer is a fake Proxmox API. It issues the ticket mapped to the
+// password or ticket sent to /access/ticket, and accepts API requests
+// authenticated with the ticket valid.
+type ticketServer struct {
+	tickets    map[string]string
+	valid      string
+	loginDelay time.Duration
+	logins     atomic.Int32
+}
+
+func (s *ticketServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/api2/json/access/ticket" {
+		s.logins.Add(1)
+		time.Sleep(s.loginDelay)
+		ticket, ok := s.tickets[r.FormValue("password")]
+		if !ok || r.FormValue("username") != "root@pam" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var resp struct {
+			Data map[string]string `json:"data"`
+		}
+		resp.Data = map[string]string{"ticket": ticket, "CSRFPreventionToken": "csrf-" + ticket}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	if c, err := r.Cookie("PVEAuthCookie"); err != nil || c.Value != s.valid {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	_, _ = w.Write([]byte(`{"data":null}`))
+}
+
+// ticketClient returns a client logged in with ticket, issued age ago.
+func ticketClient(srv *httptest.Server, ticket string, age time.Duration) *ProxmoxClient {
+	return &ProxmoxClient{
+		Endpoint:   srv.URL,
+		HTTPClient: srv.Client(),
+		Retry:      RetryOptions{MaxAttempts: 1},
+		username:   "root@pam",
+		password:   "secret",
+		ticket:     ticket,
+		csrfToken:  "csrf-" + ticket,
+		issued:     time.Now().Add(-age),
+	}
+}
+
+func TestTicketRenewal(t *testing.T) {
+	tests := []struct {
+		name       string
+		age        time.Duration
+		tickets    map[string]string
+		wantTicket string
+		wantLogins int32
+	}{
+		{
+			name:       "recent ticket used as is",
+			age:        time.Minute,
+			tickets:    map[string]string{"secret": "new"},
+			wantTicket: "old",
+		},
+		{
+			name:       "old ticket renewed with itself",
+			age:        ticketRenewAfter,
+			tickets:    map[string]string{"old": "renewed", "secret": "new"},
+			wantTicket: "renewed",
+			wantLogins: 1,
+		},
+		{
+			name:       "expired ticket replaced using the password",
+			age:        2 * time.Hour,
+			tickets:    map[string]string{"secret": "new"},
+			wantTicket: "new",
+			wantLogins: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &ticketServer{tickets: tt.tickets, valid: tt.wantTicket}
+			srv := httptest.NewServer(fake)
+			defer srv.Close()
+
+			c := ticketClient(srv, "old", tt.age)
+			resp, err := c.Request(context.Background(), http.MethodGet, "/api2/json/version", nil)
+			if err != nil {
+				t.Fatalf("Request() error = %v", err)
+			}
+			resp.Body.Close()
+			if c.ticket != tt.wantTicket || c.csrfToken != "csrf-"+tt.wantTicket {
+				t.Errorf("ticket = %q, CSRF token = %q, want %q", c.ticket, c.csrfToken, tt.wantTicket)
+			}
+			if got := fake.logins.Load(); got != tt.wantLogins {
+				t.Errorf("logins = %d, want %d", got, tt.wantLogins)
+			}
+		})
+	}
+}
+
+func TestReloginSingleFlight(t *testing.T) {
+	fake := &ticketServer{tickets: map[string]string{"secret": "new"}, valid: "new", loginDelay: 50 * time.Millisecond}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	// The ticket is recent but rejected, e.g. after the cluster key rotated.
+	c := ticketClient(srv, "revoked", time.Minute)
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := c.Request(context.Background(), http.MethodGet, "/api2/json/version", nil)
+			if err != nil {
+				errs <- err
+				return
+			}
+			resp.Body.Close()
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("Request() error = %v", err)
+	}
+	if got := fake.logins.Load(); got != 1 {
+		t.Errorf("logins = %d, want a single login shared by all requests", got)
+	}
+}
+
+func TestReloginFailure(t *testing.T) {
+	fake := &ticketServer{tickets: map[string]string{}, valid: "new"}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	c := ticketClient(srv, "revoked", time.Minute)
+	if _, err := c.Request(context.Background(), http.MethodGet, "/api2/json/version", nil); !IsUnauthorized(err) {
+		t.Errorf("Request() error = %v, want unauthorized", err)
+	}
+}
+
+func TestTokenAuth(t *testing.T) {
+	if _, err := NewClientWithToken("https://pve:8006", "root@pam", "s3cret", Options{}); err == nil {
+		t.Errorf("NewClientWithToken() error = nil for a token ID without token name")
+	}
+	if _, err := NewClientWithToken("https://pve:8006", "root@pam!ci", "", Options{}); err == nil {
+		t.Errorf("NewClientWithToken() error = nil for an empty secret")
+	}
+
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		switch {
+		case r.URL.Path == "/api2/json/access/ticket":
+			t.Errorf("%s %s: API token clients must not log in", r.Method, r.URL.Path)
+		case r.Header.Get("Authorization") != "PVEAPIToken=root@pam!ci=s3cret":
+			t.Errorf("%s %s: Authorization = %q", r.Method, r.URL.Path, r.Header.Get("Authorization"))
+		case r.Header.Get("CSRFPreventionToken") != "" || r.Header.Get("Cookie") != "":
+			t.Errorf("%s %s: unexpected ticket headers on a token request", r.Method, r.URL.Path)
+		}
+		_, _ = w.Write([]byte(`{"data":null}`))
+	}))
+	defer srv.Close()
+
+	c, err := NewClientWithToken(srv.URL, "root@pam!ci", "s3cret", Options{})
+	if err != nil {
+		t.Fatalf("NewClientWithToken() error = %v", err)
+	}
+	for _, method := range []string{http.MethodGet, http.MethodPost, http.MethodPut} {
+		resp, err := c.Request(context.Background(), method, "/api2/json/nodes/pve/qemu/101/config", map[string]interface{}{"memory": 2048})
+		if err != nil {
+			t.Fatalf("Request(%s) error = %v", method, err)
+		}
+		resp.Body.Close()
+	}
+	if got := requests.Load(); got != 3 {
+		t.Errorf("server received %d requests, want 3", got)
+	}
+}
@@ -3,22 +3,43 @@ package proxmoxclient
 import (
 	"bytes"
 	"context"
-	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"strings"
+	"sync"
+	"time"
 
 	proxmoxv1alpha1 "provider-proxmox/api/v1alpha1"
 )
 
 type ProxmoxClient struct {
-	Endpoint   string
-	Ticket     string
-	CSRFToken  string
-	HTTPClient *http.Client
+	Endpoint            string
+	Endpoints           []string // Further cluster members used when Endpoint is unreachable
+	HealthCheckInterval time.Duration
+	APIToken            string // Full "user@realm!tokenid=secret" value; when set, no ticket is used
+	HTTPClient          *http.Client
+	RequestTimeout      time.Duration // Upper bound for a single API request, including reading the response
+	Retry               RetryOptions
+
+	limiter *limiter
+
+	// Failover state, guarded by endpointMu: the index of the active endpoint
+	// and the last time a preferred endpoint was checked.
+	endpointMu sync.Mutex
+	active     int
+	checked    time.Time
+
+	// Ticket authentication state, guarded by mu. The password is kept to log
+	// in again once the ticket can no longer be renewed.
+	mu        sync.Mutex
+	username  string
+	password  string
+	ticket    string
+	csrfToken string
+	issued    time.Time
 }
 
 const (
@@ -27,84 +48,280 @@ const (
 	StatusDeleting = "deleting"
 )
 
-// NewClientWithCredentials authenticates with the Proxmox API and creates a new ProxmoxClient.
-func NewClientWithCredentials(endpoint, username, password string) (*ProxmoxClient, error) {
-	authURL := fmt.Sprintf("%s/api2/json/access/ticket", endpoint)
-	authPayload := fmt.Sprintf("username=%s&password=%s", username, password)
+// DefaultRequestTimeout is used for clients created without a request timeout.
+const DefaultRequestTimeout = 30 * time.Second
+
+// Options configures the connection of a ProxmoxClient.
+type Options struct {
+	TLS            TLSOptions
+	RequestTimeout time.Duration // Defaults to DefaultRequestTimeout
+	Retry          *RetryOptions // Defaults to DefaultRetry
+	Limits         LimitOptions
 
-	req, err := http.NewRequest("POST", authURL, bytes.NewBufferString(authPayload))
+	// Endpoints are further members of the cluster to fail over to.
+	Endpoints           []string
+	HealthCheckInterval time.Duration // Defaults to DefaultHealthCheckInterval
+}
+
+// NewClientWithCredentials authenticates with the Proxmox API and creates a new ProxmoxClient.
+func NewClientWithCredentials(ctx context.Context, endpoint, username, password string, opts Options) (*ProxmoxClient, error) {
+	client, err := newHTTPClient(opts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create auth request: %w", err)
+		return nil, err
 	}
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	c := &ProxmoxClient{
+		Endpoint:            endpoint,
+		Endpoints:           opts.Endpoints,
+		HealthCheckInterval: healthCheckInterval(opts),
+		HTTPClient:          client,
+		RequestTimeout:      requestTimeout(opts),
+		Retry:               retryOptions(opts),
+		limiter:             newLimiter(opts.Limits),
+		username:            username,
+		password:            password,
 	}
-	client := &http.Client{Transport: tr}
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to authenticate with Proxmox API: %w", err)
+	// Log in on another cluster member if the preferred one is down.
+	for range c.endpoints() {
+		active := c.ActiveEndpoint()
+		err := c.login(ctx, password)
+		if err == nil {
+			return c, nil
+		}
+		if !unreachable(err) || !c.failover(ctx, active) {
+			return nil, err
+		}
 	}
-	defer resp.Body.Close()
+	return nil, errors.New("no Proxmox endpoint is reachable")
+}
 
-	var authResponse struct {
-		Data struct {
-			Ticket              string `json:"ticket"`
-			CSRFPreventionToken string `json:"CSRFPreventionToken"`
-		} `json:"data"`
+// NewClientWithToken creates a new ProxmoxClient that authenticates every request with
+// a PVE API token. tokenID has the form "user@realm!tokenid".
+func NewClientWithToken(endpoint, tokenID, secret string, opts Options) (*ProxmoxClient, error) {
+	if !strings.Contains(tokenID, "!") {
+		return nil, fmt.Errorf("invalid API token ID %q, expected user@realm!tokenid", tokenID)
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&authResponse); err != nil {
-		return nil, fmt.Errorf("failed to parse authentication response: %w", err)
+	if secret == "" {
+		return nil, errors.New("API token secret is empty")
+	}
+	client, err := newHTTPClient(opts)
+	if err != nil {
+		return nil, err
 	}
 
 	return &ProxmoxClient{
-		Endpoint:   endpoint,
-		Ticket:     authResponse.Data.Ticket,
-		CSRFToken:  authResponse.Data.CSRFPreventionToken,
-		HTTPClient: client,
+		Endpoint:            endpoint,
+		Endpoints:           opts.Endpoints,
+		HealthCheckInterval: healthCheckInterval(opts),
+		APIToken:            fmt.Sprintf("%s=%s", tokenID, secret),
+		HTTPClient:          client,
+		RequestTimeout:      requestTimeout(opts),
+		Retry:               retryOptions(opts),
+		limiter:             newLimiter(opts.Limits),
 	}, nil
 }
 
-// Request performs an API request to Proxmox and returns the response.
-func (c *ProxmoxClient) Request(method, urlPath string, payload interface{}) (*http.Response, error) {
-	url := fmt.Sprintf("%s%s", c.Endpoint, urlPath)
-	var body io.Reader
+func requestTimeout(opts Options) time.Duration {
+	if opts.RequestTimeout > 0 {
+		return opts.RequestTimeout
+	}
+	return DefaultRequestTimeout
+}
+
+func healthCheckInterval(opts Options) time.Duration {
+	if opts.HealthCheckInterval > 0 {
+		return opts.HealthCheckInterval
+	}
+	return DefaultHealthCheckInterval
+}
 
-	if payload != nil {
-		data, err := json.Marshal(payload)
-		if err != nil {
-			return nil, fmt.Errorf("failed to marshal payload: %w", err)
+func retryOptions(opts Options) RetryOptions {
+	if opts.Retry != nil {
+		return *opts.Retry
+	}
+	return DefaultRetry
+}
+
+func newHTTPClient(opts Options) (*http.Client, error) {
+	// The transport is shared by all endpoints, whose nodes each present
+	// their own certificate.
+	if len(opts.Endpoints) > 0 && (opts.TLS.CertificateFingerprint != "" || opts.TLS.ServerName != "") {
+		return nil, errors.New("invalid TLS configuration: a certificate fingerprint or server name matches a single node " +
+			"and cannot be used with further endpoints; trust the cluster CA (/etc/pve/pve-root-ca.pem) with a CA bundle instead")
+	}
+	tlsConfig, err := opts.TLS.tlsConfig()
+	if err != nil {
+		return nil, fmt.Errorf("invalid TLS configuration: %w", err)
+	}
+	tr := &http.Transport{
+		TLSClientConfig: tlsConfig,
+	}
+	return &http.Client{Transport: tr}, nil
+}
+
+// Request performs an API request to Proxmox and returns the response. The
+// payload is encoded with EncodeParams and sent as the query string of GET and
+// DELETE requests, or as a form-encoded body otherwise. With ticket
+// authentication, a request rejected with 401 is retried once after logging in
+// again.
+//
+// Each attempt is bounded by RequestTimeout and aborted when ctx is done. The
+// timeout keeps running until the response body is closed. Requests failing
+// for a transient reason are retried with exponential backoff as described by
+// retryable, and all attempts are subject to the limits of the client. When
+// the active endpoint cannot be reached, the request is retried right away on
+// another healthy cluster member. Only GET and HEAD requests are considered
+// idempotent.
+func (c *ProxmoxClient) Request(ctx context.Context, method, urlPath string, payload interface{}) (*http.Response, error) {
+	return c.request(ctx, method, urlPath, payload, method == http.MethodGet || method == http.MethodHead)
+}
+
+// request performs an API request like Request. idempotent marks a request
+// that may be sent again after any transient failure, as it has the same
+// effect however often it is applied.
+func (c *ProxmoxClient) request(ctx context.Context, method, urlPath string, payload interface{}, idempotent bool) (*http.Response, error) {
+	params, err := EncodeParams(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode payload: %w", err)
+	}
+	var data []byte
+	if len(params) > 0 {
+		if method == http.MethodGet || method == http.MethodDelete {
+			sep := "?"
+			if strings.Contains(urlPath, "?") {
+				sep = "&"
+			}
+			urlPath += sep + params.Encode()
+		} else {
+			data = []byte(params.Encode())
 		}
-		body = bytes.NewBuffer(data)
 	}
 
-	req, err := http.NewRequest(method, url, body)
+	c.failback(ctx)
+	for attempt := 1; ; attempt++ {
+		endpoint := c.ActiveEndpoint()
+		resp, err := c.attempt(ctx, endpoint, method, urlPath, data)
+		if err == nil {
+			return resp, nil
+		}
+		switched := unreachable(err) && c.failover(ctx, endpoint)
+		if attempt >= c.Retry.MaxAttempts || !retryable(idempotent, err) || ctx.Err() != nil {
+			return nil, err
+		}
+		if switched {
+			continue
+		}
+		if sleepErr := sleep(ctx, c.Retry.backoff(attempt)); sleepErr != nil {
+			return nil, err
+		}
+	}
+}
+
+// attempt sends a request once, logging in again if the ticket was rejected.
+func (c *ProxmoxClient) attempt(ctx context.Context, endpoint, method, urlPath string, data []byte) (*http.Response, error) {
+	release := func() {}
+	if c.limiter != nil {
+		var err error
+		if release, err = c.limiter.acquire(ctx); err != nil {
+			return nil, fmt.Errorf("request to Proxmox API not sent: %w", err)
+		}
+	}
+
+	resp, err := c.send(ctx, endpoint, method, urlPath, data)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		release()
+		return nil, err
 	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Cookie", "PVEAuthCookie="+c.Ticket)
-	req.Header.Set("CSRFPreventionToken", c.CSRFToken)
+	resp.Body = &onClose{ReadCloser: resp.Body, fn: release}
+	return resp, nil
+}
 
-	resp, err := c.HTTPClient.Do(req)
+// send performs the request with the current credentials.
+func (c *ProxmoxClient) send(ctx context.Context, endpoint, method, urlPath string, data []byte) (*http.Response, error) {
+	ticket, csrfToken, err := c.credentials(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.do(ctx, endpoint, method, urlPath, data, ticket, csrfToken)
+	if err == nil && resp.StatusCode == http.StatusUnauthorized && c.APIToken == "" {
+		resp.Body.Close()
+		if ticket, csrfToken, err = c.relogin(ctx, ticket); err != nil {
+			return nil, err
+		}
+		resp, err = c.do(ctx, endpoint, method, urlPath, data, ticket, csrfToken)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("request to Proxmox API failed: %w", err)
 	}
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		defer resp.Body.Close()
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+		return nil, newAPIError(method, urlPath, resp)
+	}
+
+	return resp, nil
+}
+
+// do sends a single request authenticated with the given ticket or the API token.
+func (c *ProxmoxClient) do(ctx context.Context, endpoint, method, urlPath string, data []byte, ticket, csrfToken string) (*http.Response, error) {
+	url := fmt.Sprintf("%s%s", endpoint, urlPath)
+	var body io.Reader
+	if data != nil {
+		body = bytes.NewReader(data)
 	}
 
+	ctx, cancel := c.withTimeout(ctx)
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if data != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	if c.APIToken != "" {
+		// API tokens are stateless and exempt from CSRF protection.
+		req.Header.Set("Authorization", "PVEAPIToken="+c.APIToken)
+	} else {
+		req.Header.Set("Cookie", "PVEAuthCookie="+ticket)
+		req.Header.Set("CSRFPreventionToken", csrfToken)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &onClose{ReadCloser: resp.Body, fn: cancel}
 	return resp, nil
 }
 
+// withTimeout bounds ctx by the request timeout of the client.
+func (c *ProxmoxClient) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.RequestTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, c.RequestTimeout)
+}
+
+// onClose runs fn once the response body is closed, releasing the context and
+// the concurrency slot of a request.
+type onClose struct {
+	io.ReadCloser
+	fn   func()
+	once sync.Once
+}
+
+func (b *onClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.fn)
+	return err
+}
+
 // GetVMStatus retrieves the current status of a VM from Proxmox, directly updating VirtualMachineStatus.
-func (c *ProxmoxClient) GetVMStatus(ctx context.Context, vmid int) (*proxmoxv1alpha1.VirtualMachineStatus, error) {
-	resp, err := c.Request("GET", fmt.Sprintf("/api2/json/nodes/pve/qemu/%d/status/current", vmid), nil)
+func (c *ProxmoxClient) GetVMStatus(ctx context.Context, node string, vmid int) (*proxmoxv1alpha1.VirtualMachineStatus, error) {
+	resp, err := c.Request(ctx, "GET", fmt.Sprintf("/api2/json/nodes/%s/qemu/%d/status/current", node, vmid), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -120,43 +337,248 @@ func (c *ProxmoxClient) GetVMStatus(ctx context.Context, vmid int) (*proxmoxv1al
 
 	// Controlla se `data` è `null` (caso VM non trovata anche con `200 OK`)
 	if statusResponse.Data == nil {
-		return nil, errors.New("VM not found (data is null)")
+		return nil, fmt.Errorf("VM %d: %w (data is null)", vmid, ErrNotFound)
 	}
 
 	return statusResponse.Data, nil
 }
 
-// Create creates a new VM on Proxmox with the provided configuration.
-func (c *ProxmoxClient) Create(payload map[string]interface{}) error {
-	resp, err := c.Request("POST", "/api2/json/nodes/pve/qemu", payload)
+// GetVMConfig retrieves the current configuration of a VM from Proxmox as returned by the API.
+func (c *ProxmoxClient) GetVMConfig(ctx context.Context, node string, vmid int) (map[string]interface{}, error) {
+	resp, err := c.Request(ctx, "GET", fmt.Sprintf("/api2/json/nodes/%s/qemu/%d/config", node, vmid), nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer resp.Body.Close()
-	return nil
+
+	var configResponse struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&configResponse); err != nil {
+		return nil, fmt.Errorf("failed to parse VM config response: %w", err)
+	}
+	if configResponse.Data == nil {
+		return nil, fmt.Errorf("VM %d: %w (data is null)", vmid, ErrNotFound)
+	}
+
+	return configResponse.Data, nil
 }
 
-// Update updates the configuration of an existing VM on Proxmox.
-func (c *ProxmoxClient) Update(vmid int, payload map[string]interface{}) error {
-	resp, err := c.Request("PUT", fmt.Sprintf("/api2/json/nodes/pve/qemu/%d/config", vmid), payload)
+// ClusterResource is an entry of the /cluster/resources listing.
+type ClusterResource struct {
+	ID       string  `json:"id"`
+	Type     string  `json:"type"` // node, qemu, lxc, storage, ...
+	Node     string  `json:"node"`
+	Status   string  `json:"status"`
+	VMID     int     `json:"vmid,omitempty"`
+	Name     string  `json:"name,omitempty"`     // Name of a VM
+	Template int     `json:"template,omitempty"` // 1 for VM templates
+	Storage  string  `json:"storage,omitempty"`
+	CPU      float64 `json:"cpu,omitempty"` // CPU usage as a fraction of MaxCPU
+	MaxCPU   float64 `json:"maxcpu,omitempty"`
+	Mem      int64   `json:"mem,omitempty"` // Used memory in bytes
+	MaxMem   int64   `json:"maxmem,omitempty"`
+	Disk     int64   `json:"disk,omitempty"`
+	MaxDisk  int64   `json:"maxdisk,omitempty"`
+}
+
+// ClusterResources lists the cluster resources, optionally filtered by type ("vm", "node" or "storage").
+func (c *ProxmoxClient) ClusterResources(ctx context.Context, resourceType string) ([]ClusterResource, error) {
+	resp, err := c.Request(ctx, "GET", "/api2/json/cluster/resources", map[string]interface{}{"type": resourceType})
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer resp.Body.Close()
-	return nil
+
+	var resourcesResponse struct {
+		Data []ClusterResource `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&resourcesResponse); err != nil {
+		return nil, fmt.Errorf("failed to parse cluster resources response: %w", err)
+	}
+	return resourcesResponse.Data, nil
 }
 
-// Delete removes a VM from Proxmox.
-func (c *ProxmoxClient) Delete(vmid int) error {
-	resp, err := c.Request("DELETE", fmt.Sprintf("/api2/json/nodes/pve/qemu/%d", vmid), nil)
+// FindVMNode looks up the node a VM currently lives on across the whole cluster.
+func (c *ProxmoxClient) FindVMNode(ctx context.Context, vmid int) (string, error) {
+	resources, err := c.ClusterResources(ctx, "vm")
 	if err != nil {
-		return err
+		return "", err
+	}
+	for _, r := range resources {
+		if r.Type == "qemu" && r.VMID == vmid {
+			return r.Node, nil
+		}
+	}
+	return "", fmt.Errorf("VM %d in the cluster: %w", vmid, ErrNotFound)
+}
+
+// FindTemplate looks up a VM template by name across the whole cluster. The
+// name must identify a single template.
+func (c *ProxmoxClient) FindTemplate(ctx context.Context, name string) (*ClusterResource, error) {
+	resources, err := c.ClusterResources(ctx, "vm")
+	if err != nil {
+		return nil, err
+	}
+	var found *ClusterResource
+	for i, r := range resources {
+		if r.Type != "qemu" || r.Template != 1 || r.Name != name {
+			continue
+		}
+		if found != nil {
+			return nil, fmt.Errorf("template name %q is ambiguous: used by VMs %d and %d", name, found.VMID, r.VMID)
+		}
+		found = &resources[i]
+	}
+	if found == nil {
+		return nil, fmt.Errorf("template %q in the cluster: %w", name, ErrNotFound)
+	}
+	return found, nil
+}
+
+// NextVMID returns the lowest VMID not in use in the cluster, within the range
+// configured for the datacenter.
+func (c *ProxmoxClient) NextVMID(ctx context.Context) (int, error) {
+	return c.nextID(ctx, nil)
+}
+
+// CheckVMID checks that a VMID is not in use in the cluster, returning a
+// conflict error otherwise.
+func (c *ProxmoxClient) CheckVMID(ctx context.Context, vmid int) error {
+	_, err := c.nextID(ctx, map[string]interface{}{"vmid": vmid})
+	return err
+}
+
+func (c *ProxmoxClient) nextID(ctx context.Context, payload map[string]interface{}) (int, error) {
+	resp, err := c.Request(ctx, "GET", "/api2/json/cluster/nextid", payload)
+	if err != nil {
+		return 0, err
 	}
 	defer resp.Body.Close()
-	return nil
+
+	// Proxmox returns the VMID as a string.
+	var nextIDResponse struct {
+		Data json.Number `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&nextIDResponse); err != nil {
+		return 0, fmt.Errorf("failed to parse next VMID response: %w", err)
+	}
+	vmid, err := nextIDResponse.Data.Int64()
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse next VMID %q: %w", nextIDResponse.Data, err)
+	}
+	return int(vmid), nil
 }
 
-// IsNotFound checks if an error represents a "not found" response from Proxmox.
-func IsNotFound(err error) bool {
-	return err != nil && (strings.Contains(err.Error(), "does not exist") || strings.Contains(err.Error(), "data is null"))
+// NodeBridges lists the names of the network bridges configured on a node.
+func (c *ProxmoxClient) NodeBridges(ctx context.Context, node string) ([]string, error) {
+	resp, err := c.Request(ctx, "GET", fmt.Sprintf("/api2/json/nodes/%s/network", node), map[string]interface{}{"type": "any_bridge"})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var networkResponse struct {
+		Data []struct {
+			Iface string `json:"iface"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&networkResponse); err != nil {
+		return nil, fmt.Errorf("failed to parse node network response: %w", err)
+	}
+
+	bridges := make([]string, 0, len(networkResponse.Data))
+	for _, n := range networkResponse.Data {
+		bridges = append(bridges, n.Iface)
+	}
+	return bridges, nil
+}
+
+// Create creates a new VM on Proxmox with the provided configuration and returns the UPID of the creation task.
+func (c *ProxmoxClient) Create(ctx context.Context, node string, payload map[string]interface{}) (string, error) {
+	resp, err := c.Request(ctx, "POST", fmt.Sprintf("/api2/json/nodes/%s/qemu", node), payload)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	return decodeUPID(resp)
+}
+
+// CloneOptions are the parameters of a VM clone.
+type CloneOptions struct {
+	NewID   int    `json:"newid"`             // VMID of the new VM
+	Name    string `json:"name,omitempty"`    // Name of the new VM
+	Target  string `json:"target,omitempty"`  // Node to create the VM on, when not the node of the source
+	Full    bool   `json:"full,omitempty"`    // Copy the disks instead of linking them to those of a template
+	Storage string `json:"storage,omitempty"` // Storage for the disks of a full clone
+	Format  string `json:"format,omitempty"`  // Format of the disks of a full clone
+}
+
+// Clone creates a VM from a copy of another VM, usually a template, and
+// returns the UPID of the clone task.
+func (c *ProxmoxClient) Clone(ctx context.Context, node string, vmid int, opts CloneOptions) (string, error) {
+	resp, err := c.Request(ctx, "POST", fmt.Sprintf("/api2/json/nodes/%s/qemu/%d/clone", node, vmid), opts)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	return decodeUPID(resp)
+}
+
+// Update updates the configuration of an existing VM on Proxmox. It returns the
+// UPID of the task started by Proxmox, or an empty string if the change was applied synchronously.
+func (c *ProxmoxClient) Update(ctx context.Context, node string, vmid int, payload map[string]interface{}) (string, error) {
+	resp, err := c.Request(ctx, "PUT", fmt.Sprintf("/api2/json/nodes/%s/qemu/%d/config", node, vmid), payload)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	return decodeUPID(resp)
+}
+
+// Resize grows a disk of a VM to an absolute size such as "64G". Proxmox
+// refuses to shrink disks. It returns the UPID of the task started by Proxmox,
+// or an empty string if the disk was resized synchronously.
+func (c *ProxmoxClient) Resize(ctx context.Context, node string, vmid int, disk, size string) (string, error) {
+	payload := map[string]interface{}{"disk": disk, "size": size}
+	// Resizing to an absolute size can be repeated safely.
+	resp, err := c.request(ctx, "PUT", fmt.Sprintf("/api2/json/nodes/%s/qemu/%d/resize", node, vmid), payload, true)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	return decodeUPID(resp)
+}
+
+// MoveDisk moves a disk of a VM, with its data, to another storage, optionally
+// converting it to another format. The source volume is deleted once the copy
+// completed. It returns the UPID of the task started by Proxmox.
+func (c *ProxmoxClient) MoveDisk(ctx context.Context, node string, vmid int, disk, storage, format string) (string, error) {
+	payload := map[string]interface{}{"disk": disk, "storage": storage, "format": format, "delete": true}
+	resp, err := c.Request(ctx, "POST", fmt.Sprintf("/api2/json/nodes/%s/qemu/%d/move_disk", node, vmid), payload)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	return decodeUPID(resp)
+}
+
+// RegenerateCloudInit rebuilds the cloud-init drive of a VM from its current
+// configuration. Proxmox otherwise only does so when the VM starts.
+func (c *ProxmoxClient) RegenerateCloudInit(ctx context.Context, node string, vmid int) error {
+	resp, err := c.request(ctx, "PUT", fmt.Sprintf("/api2/json/nodes/%s/qemu/%d/cloudinit", node, vmid), nil, true)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// Delete removes a VM from Proxmox and returns the UPID of the destruction task.
+func (c *ProxmoxClient) Delete(ctx context.Context, node string, vmid int) (string, error) {
+	resp, err := c.Request(ctx, "DELETE", fmt.Sprintf("/api2/json/nodes/%s/qemu/%d", node, vmid), nil)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	return decodeUPID(resp)
 }
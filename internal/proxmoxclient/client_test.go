@@ -0,0 +1,128 @@
+package proxmoxclient
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFindTemplate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"data":[
+			{"id":"qemu/9000","type":"qemu","node":"pve1","vmid":9000,"name":"debian-12","template":1},
+			{"id":"qemu/101","type":"qemu","node":"pve1","vmid":101,"name":"ubuntu-24.04","template":0},
+			{"id":"qemu/9001","type":"qemu","node":"pve2","vmid":9001,"name":"ubuntu-24.04","template":1},
+			{"id":"qemu/9002","type":"qemu","node":"pve1","vmid":9002,"name":"rocky-9","template":1},
+			{"id":"qemu/9003","type":"qemu","node":"pve2","vmid":9003,"name":"rocky-9","template":1}
+		]}`)
+	}))
+	defer server.Close()
+	c := &ProxmoxClient{Endpoint: server.URL, APIToken: "root@pam!test=secret", HTTPClient: http.DefaultClient}
+
+	// VMs that are not templates are ignored.
+	template, err := c.FindTemplate(context.Background(), "ubuntu-24.04")
+	if err != nil {
+		t.Fatalf("FindTemplate() error = %v", err)
+	}
+	if template.VMID != 9001 || template.Node != "pve2" {
+		t.Errorf("FindTemplate() = VM %d on %s, want VM 9001 on pve2", template.VMID, template.Node)
+	}
+
+	if _, err := c.FindTemplate(context.Background(), "rocky-9"); err == nil {
+		t.Error("FindTemplate() expected an error for an ambiguous name")
+	}
+	if _, err := c.FindTemplate(context.Background(), "alpine"); !IsNotFound(err) {
+		t.Errorf("FindTemplate() error = %v, want not found", err)
+	}
+}
+
+func TestPendingChanges(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api2/json/nodes/pve1/qemu/101/pending" {
+			t.Errorf("unexpected request path %s", r.URL.Path)
+		}
+		_, _ = io.WriteString(w, `{"data":[
+			{"key":"memory","value":2048,"pending":4096},
+			{"key":"cores","value":2},
+			{"key":"net1","value":"virtio=BC:24:11:2E:4F:02,bridge=vmbr1","delete":1},
+			{"key":"digest","value":"0123"}
+		]}`)
+	}))
+	defer server.Close()
+	c := &ProxmoxClient{Endpoint: server.URL, APIToken: "root@pam!test=secret", HTTPClient: http.DefaultClient}
+
+	changes, err := c.PendingChanges(context.Background(), "pve1", 101)
+	if err != nil {
+		t.Fatalf("PendingChanges() error = %v", err)
+	}
+	var keys []string
+	for _, p := range changes {
+		keys = append(keys, p.Key)
+	}
+	if len(keys) != 2 || keys[0] != "memory" || keys[1] != "net1" {
+		t.Errorf("PendingChanges() keys = %v, want [memory net1]", keys)
+	}
+}
+
+func TestGuestAgent(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api2/json/nodes/pve1/qemu/101/agent/network-get-interfaces":
+			_, _ = io.WriteString(w, `{"data":{"result":[
+				{"name":"lo","hardware-address":"00:00:00:00:00:00","ip-addresses":[{"ip-address":"127.0.0.1","ip-address-type":"ipv4","prefix":8}]},
+				{"name":"eth0","hardware-address":"bc:24:11:2e:4f:01","ip-addresses":[{"ip-address":"10.0.0.5","ip-address-type":"ipv4","prefix":24}],"statistics":{"rx-bytes":1}}
+			]}}`)
+		case "/api2/json/nodes/pve1/qemu/101/agent/get-host-name":
+			_, _ = io.WriteString(w, `{"data":{"result":{"host-name":"web-1"}}}`)
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = io.WriteString(w, `{"message":"QEMU guest agent is not running\n","data":null}`)
+		}
+	}))
+	defer server.Close()
+	c := &ProxmoxClient{Endpoint: server.URL, APIToken: "root@pam!test=secret", HTTPClient: http.DefaultClient}
+
+	ifaces, err := c.GuestNetworkInterfaces(context.Background(), "pve1", 101)
+	if err != nil {
+		t.Fatalf("GuestNetworkInterfaces() error = %v", err)
+	}
+	if len(ifaces) != 2 || ifaces[1].Name != "eth0" || ifaces[1].IPAddresses[0].Address != "10.0.0.5" || ifaces[1].IPAddresses[0].Prefix != 24 {
+		t.Errorf("GuestNetworkInterfaces() = %+v", ifaces)
+	}
+	if hostname, err := c.GuestHostname(context.Background(), "pve1", 101); err != nil || hostname != "web-1" {
+		t.Errorf("GuestHostname() = %q, %v, want %q", hostname, err, "web-1")
+	}
+	if _, err := c.GuestNetworkInterfaces(context.Background(), "pve1", 102); !IsAgentUnavailable(err) {
+		t.Errorf("GuestNetworkInterfaces() error = %v, want agent unavailable", err)
+	}
+}
+
+func TestNextVMID(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("vmid") {
+		case "":
+			_, _ = io.WriteString(w, `{"data":"105"}`)
+		case "105":
+			_, _ = io.WriteString(w, `{"data":105}`)
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = io.WriteString(w, `{"errors":{"vmid":"VM 101 already exists"},"data":null}`)
+		}
+	}))
+	defer server.Close()
+	c := &ProxmoxClient{Endpoint: server.URL, APIToken: "root@pam!test=secret", HTTPClient: http.DefaultClient}
+
+	if vmid, err := c.NextVMID(context.Background()); err != nil || vmid != 105 {
+		t.Errorf("NextVMID() = %d, %v, want 105", vmid, err)
+	}
+	if err := c.CheckVMID(context.Background(), 105); err != nil {
+		t.Errorf("CheckVMID(105) error = %v", err)
+	}
+	if err := c.CheckVMID(context.Background(), 101); err == nil {
+		t.Error("CheckVMID(101) error = nil for a VMID in use")
+	}
+}
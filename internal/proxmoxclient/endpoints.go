@@ -0,0 +1,113 @@
+package proxmoxclient
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// DefaultHealthCheckInterval is used for clients created without a health check interval.
+const DefaultHealthCheckInterval = time.Minute
+
+// healthCheckTimeout bounds a single health check of an endpoint.
+const healthCheckTimeout = 5 * time.Second
+
+// endpoints returns the cluster members the client may send requests to, in
+// order of preference.
+func (c *ProxmoxClient) endpoints() []string {
+	return append([]string{c.Endpoint}, c.Endpoints...)
+}
+
+// ActiveEndpoint returns the endpoint requests are currently sent to.
+func (c *ProxmoxClient) ActiveEndpoint() string {
+	c.endpointMu.Lock()
+	defer c.endpointMu.Unlock()
+	return c.endpoints()[c.active]
+}
+
+// failover switches to the first healthy endpoint other than failed, unless
+// another request switched away from failed already. It reports whether
+// requests are now sent to a different endpoint.
+func (c *ProxmoxClient) failover(ctx context.Context, failed string) bool {
+	endpoints := c.endpoints()
+	if len(endpoints) < 2 {
+		return false
+	}
+	if active := c.ActiveEndpoint(); active != failed {
+		return true
+	}
+	for i, endpoint := range endpoints {
+		if endpoint != failed && c.healthy(ctx, endpoint) {
+			c.setActive(i, failed)
+			return true
+		}
+	}
+	return false
+}
+
+// failback returns to an endpoint preferred over the active one once it is
+// healthy again, checking at most once per HealthCheckInterval.
+func (c *ProxmoxClient) failback(ctx context.Context) {
+	c.endpointMu.Lock()
+	active := c.active
+	due := active > 0 && time.Since(c.checked) >= c.HealthCheckInterval
+	if due {
+		// Concurrent requests keep using the active endpoint meanwhile.
+		c.checked = time.Now()
+	}
+	c.endpointMu.Unlock()
+	if !due {
+		return
+	}
+
+	endpoints := c.endpoints()
+	for i := 0; i < active; i++ {
+		if c.healthy(ctx, endpoints[i]) {
+			c.setActive(i, endpoints[active])
+			return
+		}
+	}
+}
+
+// setActive switches to the endpoint at index i if from is still the active one.
+func (c *ProxmoxClient) setActive(i int, from string) {
+	c.endpointMu.Lock()
+	defer c.endpointMu.Unlock()
+	if c.endpoints()[c.active] == from {
+		c.active = i
+		c.checked = time.Now()
+	}
+}
+
+// healthy checks that pveproxy answers on an endpoint. Any response below 500,
+// including 401 for the unauthenticated request, means the node is up.
+func (c *ProxmoxClient) healthy(ctx context.Context, endpoint string) bool {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"/api2/json/version", nil)
+	if err != nil {
+		return false
+	}
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode < http.StatusInternalServerError
+}
+
+// unreachable checks if a request failed because the endpoint could not be
+// reached at all, as opposed to an error reported by Proxmox.
+func unreachable(err error) bool {
+	if _, ok := asAPIError(err); ok || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EHOSTUNREACH)
+}
@@ -0,0 +1,83 @@
+package proxmoxclient
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFailover(t *testing.T) {
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	var primaryUp atomic.Bool
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !primaryUp.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = io.WriteString(w, `{"data":null}`)
+	}))
+	defer primary.Close()
+	backup := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"data":null}`)
+	}))
+	defer backup.Close()
+
+	c := &ProxmoxClient{
+		Endpoint:            down.URL,
+		Endpoints:           []string{backup.URL},
+		HealthCheckInterval: time.Hour,
+		APIToken:            "root@pam!test=secret",
+		HTTPClient:          http.DefaultClient,
+		Retry:               RetryOptions{MaxAttempts: 2, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond},
+	}
+	resp, err := c.Request(context.Background(), http.MethodGet, "/api2/json/version", nil)
+	if err != nil {
+		t.Fatalf("Request() error = %v, want failover to the backup endpoint", err)
+	}
+	resp.Body.Close()
+	if got := c.ActiveEndpoint(); got != backup.URL {
+		t.Errorf("ActiveEndpoint() = %q, want %q", got, backup.URL)
+	}
+
+	// Return to the preferred endpoint once it is healthy again.
+	c.Endpoint = primary.URL
+	c.failback(context.Background())
+	if got := c.ActiveEndpoint(); got != backup.URL {
+		t.Errorf("ActiveEndpoint() = %q before the health check interval, want %q", got, backup.URL)
+	}
+	c.HealthCheckInterval = time.Nanosecond
+	c.failback(context.Background())
+	if got := c.ActiveEndpoint(); got != backup.URL {
+		t.Errorf("ActiveEndpoint() = %q while the primary is unhealthy, want %q", got, backup.URL)
+	}
+	primaryUp.Store(true)
+	c.failback(context.Background())
+	if got := c.ActiveEndpoint(); got != primary.URL {
+		t.Errorf("ActiveEndpoint() = %q, want %q", got, primary.URL)
+	}
+}
+
+func TestEndpointsRejectSingleNodeTLS(t *testing.T) {
+	fingerprint := "AB:CD:EF:01:23:45:67:89:AB:CD:EF:01:23:45:67:89:AB:CD:EF:01:23:45:67:89:AB:CD:EF:01:23:45:67:89"
+	for name, tlsOpts := range map[string]TLSOptions{
+		"fingerprint": {CertificateFingerprint: fingerprint},
+		"server name": {ServerName: "pve1.example.com"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			opts := Options{TLS: tlsOpts}
+			if _, err := NewClientWithToken("https://pve1:8006", "root@pam!test", "secret", opts); err != nil {
+				t.Fatalf("NewClientWithToken() error = %v for a single endpoint", err)
+			}
+			opts.Endpoints = []string{"https://pve2:8006"}
+			if _, err := NewClientWithToken("https://pve1:8006", "root@pam!test", "secret", opts); err == nil {
+				t.Errorf("NewClientWithToken() error = nil with endpoints, want an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package proxmoxclient
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// ErrNotFound is returned when Proxmox answers successfully but the requested
+// object does not exist.
+var ErrNotFound = errors.New("not found")
+
+// APIError is an error response returned by the Proxmox API.
+type APIError struct {
+	Method     string
+	Path       string
+	StatusCode int
+	Message    string            // Reason reported by Proxmox, e.g. "VM 101 already exists on node 'pve'"
+	Errors     map[string]string // Per-parameter validation messages
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("%s %s: API request failed with status %d", e.Method, e.Path, e.StatusCode)
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	if len(e.Errors) > 0 {
+		keys := make([]string, 0, len(e.Errors))
+		for k := range e.Errors {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		parts := make([]string, 0, len(keys))
+		for _, k := range keys {
+			parts = append(parts, fmt.Sprintf("%s: %s", k, strings.TrimSpace(e.Errors[k])))
+		}
+		msg += " (" + strings.Join(parts, "; ") + ")"
+	}
+	return msg
+}
+
+// newAPIError builds an APIError from an unsuccessful response. Proxmox puts
+// the reason in the HTTP status line and parameter errors in the body.
+func newAPIError(method, path string, resp *http.Response) *APIError {
+	apiErr := &APIError{
+		Method:     method,
+		Path:       path,
+		StatusCode: resp.StatusCode,
+		Message:    strings.TrimSpace(strings.TrimPrefix(resp.Status, strconv.Itoa(resp.StatusCode))),
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	var errorResponse struct {
+		Message string            `json:"message"`
+		Errors  map[string]string `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &errorResponse); err == nil {
+		if errorResponse.Message != "" {
+			apiErr.Message = strings.TrimSpace(errorResponse.Message)
+		}
+		apiErr.Errors = errorResponse.Errors
+	} else if len(body) > 0 && apiErr.Message == "" {
+		apiErr.Message = strings.TrimSpace(string(body))
+	}
+	return apiErr
+}
+
+func asAPIError(err error) (*APIError, bool) {
+	var apiErr *APIError
+	ok := errors.As(err, &apiErr)
+	return apiErr, ok
+}
+
+func (e *APIError) messageContains(substrings ...string) bool {
+	msg := strings.ToLower(e.Message)
+	for _, s := range substrings {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsNotFound checks if an error represents a "not found" response from Proxmox.
+// Proxmox reports missing VMs and tasks with a 500 status, so the reason is
+// inspected as well as the status code.
+func IsNotFound(err error) bool {
+	if errors.Is(err, ErrNotFound) {
+		return true
+	}
+	apiErr, ok := asAPIError(err)
+	if !ok {
+		return false
+	}
+	return apiErr.StatusCode == http.StatusNotFound ||
+		(apiErr.StatusCode == http.StatusInternalServerError && apiErr.messageContains("does not exist", "no such"))
+}
+
+// IsUnauthorized checks if the request was rejected because the credentials are
+// invalid or lack the required privileges.
+func IsUnauthorized(err error) bool {
+	apiErr, ok := asAPIError(err)
+	return ok && (apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden)
+}
+
+// IsLocked checks if the request failed because the VM is locked by another
+// operation, such as a backup, migration or a task still in progress. Proxmox
+// reports the latter as "can't lock file '...' - got timeout".
+func IsLocked(err error) bool {
+	apiErr, ok := asAPIError(err)
+	return ok && apiErr.messageContains("is locked", "can't lock file")
+}
+
+// IsConflict checks if the request conflicts with an existing object, such as
+// creating a VM with a VMID already in use.
+func IsConflict(err error) bool {
+	apiErr, ok := asAPIError(err)
+	return ok && (apiErr.StatusCode == http.StatusConflict || apiErr.messageContains("already exists"))
+}
+
+// IsValidation checks if Proxmox rejected the request parameters.
+func IsValidation(err error) bool {
+	apiErr, ok := asAPIError(err)
+	return ok && (apiErr.StatusCode == http.StatusBadRequest || len(apiErr.Errors) > 0)
+}
+
+// IsAgentUnavailable checks if a guest agent request failed because the agent
+// is not enabled, not installed or not responding in the guest.
+func IsAgentUnavailable(err error) bool {
+	apiErr, ok := asAPIError(err)
+	return ok && apiErr.messageContains("guest agent is not running", "no qemu guest agent configured", "qmp command 'guest-")
+}
+
+// StatusProxyError is the non-standard status pveproxy uses when it cannot
+// reach the node serving a request, e.g. "596 Connection timed out".
+const StatusProxyError = 596
+
+// IsRetryable checks if a request failed for a transient reason, such as an
+// overloaded pveproxy or a dropped connection, and may succeed when repeated.
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if apiErr, ok := asAPIError(err); ok {
+		switch apiErr.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout, StatusProxyError:
+			return true
+		}
+		return false
+	}
+
+	var certErr *tls.CertificateVerificationError
+	if errors.As(err, &certErr) {
+		return false
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) ||
+		errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED)
+}
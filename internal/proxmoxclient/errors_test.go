@@ -0,0 +1,120 @@
+package proxmoxclient
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// response builds an error response the way pveproxy sends it, with the
+// reason in the status line and a JSON body.
+func response(status string, body string) *http.Response {
+	var code int
+	fmt.Sscanf(status, "%d", &code)
+	return &http.Response{StatusCode: code, Status: status, Body: io.NopCloser(strings.NewReader(body))}
+}
+
+func TestNewAPIError(t *testing.T) {
+	tests := []struct {
+		name        string
+		resp        *http.Response
+		wantMessage string
+		wantErrors  map[string]string
+		wantString  string
+	}{
+		{
+			name:        "reason from status line",
+			resp:        response("500 Configuration file 'nodes/pve/qemu-server/999.conf' does not exist", `{"data":null}`),
+			wantMessage: "Configuration file 'nodes/pve/qemu-server/999.conf' does not exist",
+			wantString:  "GET /api2/json/nodes/pve/qemu/999/config: API request failed with status 500: Configuration file 'nodes/pve/qemu-server/999.conf' does not exist",
+		},
+		{
+			name:        "parameter errors",
+			resp:        response("400 Parameter verification failed.", `{"errors":{"memory":"value must have a minimum value of 16\n","cores":"type check ('integer') failed - got 'x'\n"},"data":null}`),
+			wantMessage: "Parameter verification failed.",
+			wantErrors:  map[string]string{"memory": "value must have a minimum value of 16\n", "cores": "type check ('integer') failed - got 'x'\n"},
+			wantString:  "GET /api2/json/nodes/pve/qemu/999/config: API request failed with status 400: Parameter verification failed. (cores: type check ('integer') failed - got 'x'; memory: value must have a minimum value of 16)",
+		},
+		{
+			name:        "message in body",
+			resp:        response("500 ", `{"message":"VM 101 already exists on node 'pve'\n"}`),
+			wantMessage: "VM 101 already exists on node 'pve'",
+			wantString:  "GET /api2/json/nodes/pve/qemu/999/config: API request failed with status 500: VM 101 already exists on node 'pve'",
+		},
+		{
+			name:        "plain text body",
+			resp:        response("502 ", "Bad Gateway\n"),
+			wantMessage: "Bad Gateway",
+			wantString:  "GET /api2/json/nodes/pve/qemu/999/config: API request failed with status 502: Bad Gateway",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := newAPIError(http.MethodGet, "/api2/json/nodes/pve/qemu/999/config", tt.resp)
+			if err.Message != tt.wantMessage {
+				t.Errorf("Message = %q, want %q", err.Message, tt.wantMessage)
+			}
+			if len(err.Errors) != len(tt.wantErrors) {
+				t.Errorf("Errors = %v, want %v", err.Errors, tt.wantErrors)
+			}
+			for k, v := range tt.wantErrors {
+				if err.Errors[k] != v {
+					t.Errorf("Errors[%q] = %q, want %q", k, err.Errors[k], v)
+				}
+			}
+			if err.Error() != tt.wantString {
+				t.Errorf("Error() = %q, want %q", err.Error(), tt.wantString)
+			}
+		})
+	}
+}
+
+func TestClassifiers(t *testing.T) {
+	apiErr := func(status, body string) error {
+		return fmt.Errorf("cannot read VM: %w", newAPIError(http.MethodGet, "/api2/json/nodes/pve/qemu/101/config", response(status, body)))
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want string // Name of the only classifier expected to match
+	}{
+		{name: "missing VM", err: apiErr("500 Configuration file 'nodes/pve/qemu-server/101.conf' does not exist", `{"data":null}`), want: "NotFound"},
+		{name: "missing task", err: apiErr("500 no such task", `{"data":null}`), want: "NotFound"},
+		{name: "null data", err: fmt.Errorf("VM 101: %w (data is null)", ErrNotFound), want: "NotFound"},
+		{name: "lock timeout", err: apiErr("500 can't lock file '/var/lock/qemu-server/lock-101.conf' - got timeout", `{"data":null}`), want: "Locked"},
+		{name: "locked by backup", err: apiErr("500 VM 101 is locked (backup)", `{"data":null}`), want: "Locked"},
+		{name: "agent timeout", err: apiErr("500 VM 101 qmp command 'guest-ping' failed - got timeout", `{"data":null}`), want: "AgentUnavailable"},
+		{name: "agent not running", err: apiErr("500 QEMU guest agent is not running", `{"data":null}`), want: "AgentUnavailable"},
+		{name: "bad ticket", err: apiErr("401 No ticket", ""), want: "Unauthorized"},
+		{name: "missing privilege", err: apiErr("403 Permission check failed (/vms/101, VM.Config.Memory)", `{"data":null}`), want: "Unauthorized"},
+		{name: "invalid parameter", err: apiErr("400 Parameter verification failed.", `{"errors":{"memory":"value must have a minimum value of 16"},"data":null}`), want: "Validation"},
+		{name: "existing VMID", err: apiErr("500 VM 101 already exists on node 'pve'", `{"data":null}`), want: "Conflict"},
+		{name: "overloaded proxy", err: apiErr("503 Service Unavailable", ""), want: "Retryable"},
+		{name: "unreachable node", err: apiErr("596 Connection timed out", ""), want: "Retryable"},
+		{name: "connection reset", err: fmt.Errorf("request to Proxmox API failed: %w", io.ErrUnexpectedEOF), want: "Retryable"},
+	}
+
+	classifiers := map[string]func(error) bool{
+		"NotFound":         IsNotFound,
+		"Locked":           IsLocked,
+		"Unauthorized":     IsUnauthorized,
+		"Validation":       IsValidation,
+		"Conflict":         IsConflict,
+		"Retryable":        IsRetryable,
+		"AgentUnavailable": IsAgentUnavailable,
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, is := range classifiers {
+				if got := is(tt.err); got != (name == tt.want) {
+					t.Errorf("Is%s(%v) = %v", name, tt.err, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package proxmoxclient
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// unset is the type of Unset.
+type unset struct{}
+
+// Unset marks a parameter to be removed from the VM config. Unset keys are
+// sent together in the "delete" parameter, as Proxmox expects.
+var Unset = unset{}
+
+// EncodeParams converts a payload into the form values understood by the
+// Proxmox API. The payload is either a map[string]interface{} or a struct,
+// whose fields are named after their json tag and skipped when tagged
+// omitempty and empty.
+//
+// Booleans are sent as 0 or 1, string slices as comma separated lists, and
+// empty strings and nil values are omitted. Values set to Unset are collected
+// into the "delete" parameter.
+func EncodeParams(payload interface{}) (url.Values, error) {
+	values := url.Values{}
+	if payload == nil {
+		return values, nil
+	}
+
+	var deletes []string
+	add := func(key string, v reflect.Value) error {
+		if v.IsValid() && v.Kind() == reflect.Interface && !v.IsNil() {
+			v = v.Elem()
+		}
+		if v.IsValid() && v.Type() == reflect.TypeOf(Unset) {
+			deletes = append(deletes, key)
+			return nil
+		}
+		s, ok, err := encodeValue(v)
+		if err != nil {
+			return fmt.Errorf("parameter %q: %w", key, err)
+		}
+		if ok {
+			values.Set(key, s)
+		}
+		return nil
+	}
+
+	v := reflect.ValueOf(payload)
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return values, nil
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("unsupported payload type %s", v.Type())
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			if err := add(iter.Key().String(), iter.Value()); err != nil {
+				return nil, err
+			}
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = strings.ToLower(f.Name)
+			}
+			fv := v.Field(i)
+			if strings.Contains(opts, "omitempty") && fv.IsZero() {
+				continue
+			}
+			if err := add(name, fv); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported payload type %s", v.Type())
+	}
+
+	if len(deletes) > 0 {
+		sort.Strings(deletes)
+		if existing := values.Get("delete"); existing != "" {
+			deletes = append([]string{existing}, deletes...)
+		}
+		values.Set("delete", strings.Join(deletes, ","))
+	}
+	return values, nil
+}
+
+// encodeValue renders a single parameter value. It returns false for values that should be omitted.
+func encodeValue(v reflect.Value) (string, bool, error) {
+	for v.IsValid() && (v.Kind() == reflect.Interface || v.Kind() == reflect.Pointer) {
+		if v.IsNil() {
+			return "", false, nil
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return "", false, nil
+	}
+
+	if s, ok := v.Interface().(fmt.Stringer); ok {
+		str := s.String()
+		return str, str != "", nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), v.String() != "", nil
+	case reflect.Bool:
+		if v.Bool() {
+			return "1", true, nil
+		}
+		return "0", true, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), true, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), true, nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64), true, nil
+	case reflect.Slice, reflect.Array:
+		items := make([]string, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			s, ok, err := encodeValue(v.Index(i))
+			if err != nil {
+				return "", false, err
+			}
+			if ok {
+				items = append(items, s)
+			}
+		}
+		return strings.Join(items, ","), len(items) > 0, nil
+	default:
+		return "", false, fmt.Errorf("unsupported value type %s", v.Type())
+	}
+}
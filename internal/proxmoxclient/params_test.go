@@ -0,0 +1,76 @@
+package proxmoxclient
+
+import "testing"
+
+func TestEncodeParams(t *testing.T) {
+	type config struct {
+		Name    string   `json:"name,omitempty"`
+		Cores   int      `json:"cores,omitempty"`
+		Numa    bool     `json:"numa"`
+		Tags    []string `json:"tags,omitempty"`
+		Skipped string   `json:"-"`
+	}
+
+	tests := []struct {
+		name    string
+		payload interface{}
+		want    string
+	}{
+		{
+			name:    "nil payload",
+			payload: nil,
+			want:    "",
+		},
+		{
+			name:    "escaping",
+			payload: map[string]interface{}{"username": "root@pam", "password": "a&b=c d+e"},
+			want:    "password=a%26b%3Dc+d%2Be&username=root%40pam",
+		},
+		{
+			name:    "scalars",
+			payload: map[string]interface{}{"vmid": 100, "numa": true, "balloon": false, "cpuunits": uint(1024), "cpulimit": 1.5},
+			want:    "balloon=0&cpulimit=1.5&cpuunits=1024&numa=1&vmid=100",
+		},
+		{
+			name:    "empty and nil values are omitted",
+			payload: map[string]interface{}{"name": "", "ide2": nil, "memory": 2048},
+			want:    "memory=2048",
+		},
+		{
+			name:    "lists and unset keys",
+			payload: map[string]interface{}{"tags": []string{"a", "b"}, "net1": Unset, "ide2": Unset},
+			want:    "delete=ide2%2Cnet1&tags=a%2Cb",
+		},
+		{
+			name:    "struct",
+			payload: &config{Name: "vm", Tags: []string{"x"}, Skipped: "y"},
+			want:    "name=vm&numa=0&tags=x",
+		},
+		{
+			name:    "clone options",
+			payload: CloneOptions{NewID: 101, Name: "web", Target: "pve2"},
+			want:    "name=web&newid=101&target=pve2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := EncodeParams(tt.payload)
+			if err != nil {
+				t.Fatalf("EncodeParams() error = %v", err)
+			}
+			if got.Encode() != tt.want {
+				t.Errorf("EncodeParams() = %q, want %q", got.Encode(), tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodeParamsUnsupported(t *testing.T) {
+	if _, err := EncodeParams(map[string]interface{}{"bad": map[string]string{}}); err == nil {
+		t.Error("EncodeParams() expected an error for a nested map")
+	}
+	if _, err := EncodeParams(42); err == nil {
+		t.Error("EncodeParams() expected an error for a non map or struct payload")
+	}
+}
@@ -0,0 +1,95 @@
+package proxmoxclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Start starts a stopped VM, or resumes a VM suspended to disk, and returns
+// the UPID of the start task.
+func (c *ProxmoxClient) Start(ctx context.Context, node string, vmid int) (string, error) {
+	return c.powerAction(ctx, node, vmid, "start", nil)
+}
+
+// Shutdown asks the guest to shut down and returns the UPID of the shutdown
+// task. The VM is stopped hard when the guest has not shut down after
+// timeout; zero selects the Proxmox default of 3 minutes.
+func (c *ProxmoxClient) Shutdown(ctx context.Context, node string, vmid int, timeout time.Duration) (string, error) {
+	payload := map[string]interface{}{"forceStop": true}
+	if timeout > 0 {
+		payload["timeout"] = int(timeout.Seconds())
+	}
+	return c.powerAction(ctx, node, vmid, "shutdown", payload)
+}
+
+// Stop stops a VM right away, like pulling its power plug, and returns the
+// UPID of the stop task.
+func (c *ProxmoxClient) Stop(ctx context.Context, node string, vmid int) (string, error) {
+	return c.powerAction(ctx, node, vmid, "stop", nil)
+}
+
+// Reboot shuts the VM down and starts it again, applying pending changes, and
+// returns the UPID of the reboot task. The task fails when the guest has not
+// shut down after timeout; zero selects the Proxmox default.
+func (c *ProxmoxClient) Reboot(ctx context.Context, node string, vmid int, timeout time.Duration) (string, error) {
+	payload := map[string]interface{}{}
+	if timeout > 0 {
+		payload["timeout"] = int(timeout.Seconds())
+	}
+	return c.powerAction(ctx, node, vmid, "reboot", payload)
+}
+
+// Suspend pauses a running VM, or saves its memory and stops it when toDisk is
+// set, and returns the UPID of the suspend task.
+func (c *ProxmoxClient) Suspend(ctx context.Context, node string, vmid int, toDisk bool) (string, error) {
+	return c.powerAction(ctx, node, vmid, "suspend", map[string]interface{}{"todisk": toDisk})
+}
+
+// Resume resumes a paused VM and returns the UPID of the resume task.
+func (c *ProxmoxClient) Resume(ctx context.Context, node string, vmid int) (string, error) {
+	return c.powerAction(ctx, node, vmid, "resume", nil)
+}
+
+func (c *ProxmoxClient) powerAction(ctx context.Context, node string, vmid int, action string, payload map[string]interface{}) (string, error) {
+	resp, err := c.Request(ctx, "POST", fmt.Sprintf("/api2/json/nodes/%s/qemu/%d/status/%s", node, vmid, action), payload)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	return decodeUPID(resp)
+}
+
+// PendingChange is a config change Proxmox applies on the next start of the VM.
+type PendingChange struct {
+	Key     string      `json:"key"`
+	Value   interface{} `json:"value,omitempty"`   // Value the VM runs with
+	Pending interface{} `json:"pending,omitempty"` // Value applied on the next start
+	Delete  int         `json:"delete,omitempty"`  // Non-zero when the key is removed on the next start
+}
+
+// PendingChanges lists the config changes of a running VM that Proxmox could
+// not hot-plug and applies on the next start.
+func (c *ProxmoxClient) PendingChanges(ctx context.Context, node string, vmid int) ([]PendingChange, error) {
+	resp, err := c.Request(ctx, "GET", fmt.Sprintf("/api2/json/nodes/%s/qemu/%d/pending", node, vmid), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var pendingResponse struct {
+		Data []PendingChange `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&pendingResponse); err != nil {
+		return nil, fmt.Errorf("failed to parse VM pending changes response: %w", err)
+	}
+
+	var changes []PendingChange
+	for _, p := range pendingResponse.Data {
+		if p.Pending != nil || p.Delete != 0 {
+			changes = append(changes, p)
+		}
+	}
+	return changes, nil
+}
@@ -0,0 +1,112 @@
+package proxmoxclient
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"syscall"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// RetryOptions controls how failed requests are retried.
+type RetryOptions struct {
+	MaxAttempts int           // Attempts per request, including the first one
+	BaseDelay   time.Duration // Delay before the first retry, doubled for each following one
+	MaxDelay    time.Duration // Upper bound for the delay between two attempts
+}
+
+// DefaultRetry is used for clients created without retry options.
+var DefaultRetry = RetryOptions{
+	MaxAttempts: 4,
+	BaseDelay:   250 * time.Millisecond,
+	MaxDelay:    5 * time.Second,
+}
+
+// LimitOptions throttles the requests sent to a Proxmox endpoint.
+type LimitOptions struct {
+	RequestsPerSecond float64 // Sustained request rate; zero disables rate limiting
+	Burst             int     // Requests allowed above the rate at once; defaults to 1
+	MaxConcurrent     int     // Requests in flight at the same time; zero means unlimited
+}
+
+// limiter enforces the LimitOptions of an endpoint.
+type limiter struct {
+	rate  *rate.Limiter
+	slots chan struct{}
+}
+
+func newLimiter(opts LimitOptions) *limiter {
+	l := &limiter{}
+	if opts.RequestsPerSecond > 0 {
+		burst := opts.Burst
+		if burst < 1 {
+			burst = 1
+		}
+		l.rate = rate.NewLimiter(rate.Limit(opts.RequestsPerSecond), burst)
+	}
+	if opts.MaxConcurrent > 0 {
+		l.slots = make(chan struct{}, opts.MaxConcurrent)
+	}
+	return l
+}
+
+// acquire waits until a request may be sent. The returned function releases
+// the concurrency slot taken by the request.
+func (l *limiter) acquire(ctx context.Context) (func(), error) {
+	if l.rate != nil {
+		if err := l.rate.Wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if l.slots == nil {
+		return func() {}, nil
+	}
+	select {
+	case l.slots <- struct{}{}:
+		return func() { <-l.slots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// retryable reports whether a failed request may be sent again. Idempotent
+// requests are retried on any transient failure, others only when the
+// connection was refused and the request never reached Proxmox: a request
+// that timed out may have been applied, e.g. allocating a disk of a config
+// update, and sending it again would apply it twice.
+func retryable(idempotent bool, err error) bool {
+	if idempotent {
+		return IsRetryable(err)
+	}
+	return errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// backoff returns the jittered delay before the given retry, counting from 1.
+func (o RetryOptions) backoff(retry int) time.Duration {
+	d := o.BaseDelay
+	for i := 1; i < retry && d < o.MaxDelay; i++ {
+		d *= 2
+	}
+	if d > o.MaxDelay {
+		d = o.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	// Spread retries of concurrent reconciles over [d/2, d).
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,78 @@
+package proxmoxclient
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRequestRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		idempotent   bool
+		status       int
+		wantAttempts int32
+		wantErr      bool
+	}{
+		{name: "GET retried until success", method: http.MethodGet, status: http.StatusServiceUnavailable, wantAttempts: 3},
+		{name: "POST not retried", method: http.MethodPost, status: http.StatusServiceUnavailable, wantAttempts: 1, wantErr: true},
+		{name: "PUT not retried", method: http.MethodPut, status: http.StatusServiceUnavailable, wantAttempts: 1, wantErr: true},
+		{name: "idempotent PUT retried", method: http.MethodPut, idempotent: true, status: http.StatusServiceUnavailable, wantAttempts: 3},
+		{name: "permanent failure not retried", method: http.MethodGet, status: http.StatusBadRequest, wantAttempts: 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if attempts.Add(1) < 3 {
+					w.WriteHeader(tt.status)
+					return
+				}
+				_, _ = io.WriteString(w, `{"data":null}`)
+			}))
+			defer srv.Close()
+
+			c := &ProxmoxClient{
+				Endpoint:   srv.URL,
+				APIToken:   "root@pam!test=secret",
+				HTTPClient: srv.Client(),
+				Retry:      RetryOptions{MaxAttempts: 4, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond},
+				limiter:    newLimiter(LimitOptions{MaxConcurrent: 1}),
+			}
+			request := c.Request
+			if tt.idempotent {
+				request = func(ctx context.Context, method, urlPath string, payload interface{}) (*http.Response, error) {
+					return c.request(ctx, method, urlPath, payload, true)
+				}
+			}
+			resp, err := request(context.Background(), tt.method, "/api2/json/version", nil)
+			if err == nil {
+				resp.Body.Close()
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Request() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := attempts.Load(); got != tt.wantAttempts {
+				t.Errorf("Request() made %d attempts, want %d", got, tt.wantAttempts)
+			}
+			if err != nil && IsRetryable(err) != (tt.status == http.StatusServiceUnavailable) {
+				t.Errorf("IsRetryable(%v) = %v", err, IsRetryable(err))
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	o := RetryOptions{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	for retry, max := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 5: time.Second, 20: time.Second} {
+		if d := o.backoff(retry); d < max/2 || d > max {
+			t.Errorf("backoff(%d) = %s, want within [%s, %s]", retry, d, max/2, max)
+		}
+	}
+}
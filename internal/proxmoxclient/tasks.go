@@ -0,0 +1,140 @@
+package proxmoxclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Task states reported by /nodes/{node}/tasks/{upid}/status.
+const (
+	TaskRunning = "running"
+	TaskStopped = "stopped"
+)
+
+// TaskStatus is the state of an asynchronous Proxmox task.
+type TaskStatus struct {
+	UPID       string `json:"upid"`
+	Node       string `json:"node"`
+	Type       string `json:"type"`
+	Status     string `json:"status"`               // running or stopped
+	ExitStatus string `json:"exitstatus,omitempty"` // OK, WARNINGS: n, or the error message once stopped
+}
+
+// Running reports whether the task has not finished yet.
+func (t *TaskStatus) Running() bool {
+	return t.Status == TaskRunning
+}
+
+// Succeeded reports whether the task finished without errors. Tasks that
+// only logged warnings are considered successful, as in the Proxmox UI.
+func (t *TaskStatus) Succeeded() bool {
+	return t.Status == TaskStopped && (t.ExitStatus == "OK" || strings.HasPrefix(t.ExitStatus, "WARNINGS"))
+}
+
+// UPID identifies a Proxmox task, formatted as
+// "UPID:node:pid:pstart:starttime:type:id:user:".
+type UPID struct {
+	Node string
+	Type string
+	ID   string
+	User string
+}
+
+// ParseUPID extracts the fields of a task identifier.
+func ParseUPID(s string) (*UPID, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) < 9 || parts[0] != "UPID" {
+		return nil, fmt.Errorf("invalid task UPID %q", s)
+	}
+	return &UPID{Node: parts[1], Type: parts[5], ID: parts[6], User: parts[7]}, nil
+}
+
+// decodeUPID reads the task identifier returned by asynchronous API calls. It
+// returns an empty string for calls that completed synchronously.
+func decodeUPID(resp *http.Response) (string, error) {
+	var taskResponse struct {
+		Data interface{} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&taskResponse); err != nil {
+		return "", fmt.Errorf("failed to parse task response: %w", err)
+	}
+	upid, _ := taskResponse.Data.(string)
+	return upid, nil
+}
+
+// GetTaskStatus retrieves the state of a task running on a node.
+func (c *ProxmoxClient) GetTaskStatus(ctx context.Context, upid string) (*TaskStatus, error) {
+	u, err := ParseUPID(upid)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.Request(ctx, "GET", fmt.Sprintf("/api2/json/nodes/%s/tasks/%s/status", u.Node, url.PathEscape(upid)), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var statusResponse struct {
+		Data *TaskStatus `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&statusResponse); err != nil {
+		return nil, fmt.Errorf("failed to parse task status response: %w", err)
+	}
+	if statusResponse.Data == nil {
+		return nil, fmt.Errorf("task %s: %w", upid, ErrNotFound)
+	}
+	return statusResponse.Data, nil
+}
+
+// GetTaskLog returns the last lines of a task log. Proxmox pages task logs
+// from their first line, so the length of the log is read first.
+func (c *ProxmoxClient) GetTaskLog(ctx context.Context, upid string, lines int) ([]string, error) {
+	u, err := ParseUPID(upid)
+	if err != nil {
+		return nil, err
+	}
+	path := fmt.Sprintf("/api2/json/nodes/%s/tasks/%s/log", u.Node, url.PathEscape(upid))
+
+	head, total, err := c.taskLog(ctx, path, 0, 1)
+	if err != nil || total <= len(head) {
+		return head, err
+	}
+	start := total - lines
+	if start < 0 {
+		start = 0
+	}
+	tail, _, err := c.taskLog(ctx, path, start, lines)
+	return tail, err
+}
+
+// taskLog returns limit lines of a task log from the 0-based line start, and
+// the number of lines of the whole log.
+func (c *ProxmoxClient) taskLog(ctx context.Context, path string, start, limit int) ([]string, int, error) {
+	resp, err := c.Request(ctx, "GET", path, map[string]interface{}{"start": start, "limit": limit})
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	var logResponse struct {
+		Data []struct {
+			N int    `json:"n"`
+			T string `json:"t"`
+		} `json:"data"`
+		Total int `json:"total"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&logResponse); err != nil {
+		return nil, 0, fmt.Errorf("failed to parse task log response: %w", err)
+	}
+
+	out := make([]string, 0, len(logResponse.Data))
+	for _, l := range logResponse.Data {
+		out = append(out, l.T)
+	}
+	return out, logResponse.Total, nil
+}
@@ -0,0 +1,104 @@
+package proxmoxclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+const testUPID = "UPID:pve:000F4240:0098967F:65A1B2C3:qmcreate:101:root@pam:"
+
+func TestParseUPID(t *testing.T) {
+	tests := []struct {
+		name    string
+		upid    string
+		want    *UPID
+		wantErr bool
+	}{
+		{name: "create task", upid: testUPID, want: &UPID{Node: "pve", Type: "qmcreate", ID: "101", User: "root@pam"}},
+		{name: "API token user", upid: "UPID:pve2:00001234:00005678:65A1B2C3:qmstart:105:root@pam!ci:", want: &UPID{Node: "pve2", Type: "qmstart", ID: "105", User: "root@pam!ci"}},
+		{name: "empty", upid: "", wantErr: true},
+		{name: "missing fields", upid: "UPID:pve:000F4240:qmcreate:101", wantErr: true},
+		{name: "wrong prefix", upid: "TASK:pve:000F4240:0098967F:65A1B2C3:qmcreate:101:root@pam:", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseUPID(tt.upid)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseUPID() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseUPID() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTaskStatus(t *testing.T) {
+	tests := []struct {
+		status        TaskStatus
+		wantRunning   bool
+		wantSucceeded bool
+	}{
+		{status: TaskStatus{Status: TaskRunning}, wantRunning: true},
+		{status: TaskStatus{Status: TaskStopped, ExitStatus: "OK"}, wantSucceeded: true},
+		{status: TaskStatus{Status: TaskStopped, ExitStatus: "WARNINGS: 2"}, wantSucceeded: true},
+		{status: TaskStatus{Status: TaskStopped, ExitStatus: "unable to create VM 101 - storage 'ceph' does not exist"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.status.Status+" "+tt.status.ExitStatus, func(t *testing.T) {
+			if got := tt.status.Running(); got != tt.wantRunning {
+				t.Errorf("Running() = %v, want %v", got, tt.wantRunning)
+			}
+			if got := tt.status.Succeeded(); got != tt.wantSucceeded {
+				t.Errorf("Succeeded() = %v, want %v", got, tt.wantSucceeded)
+			}
+		})
+	}
+}
+
+func TestGetTaskLog(t *testing.T) {
+	// Proxmox returns limit lines from start, numbered from 1, with the total.
+	const total = 100
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api2/json/nodes/pve/tasks/"+testUPID+"/log" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		start, _ := strconv.Atoi(r.URL.Query().Get("start"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		type line struct {
+			N int    `json:"n"`
+			T string `json:"t"`
+		}
+		lines := []line{}
+		for n := start + 1; n <= total && n <= start+limit; n++ {
+			lines = append(lines, line{N: n, T: "line " + strconv.Itoa(n)})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": lines, "total": total})
+	}))
+	defer srv.Close()
+
+	c := &ProxmoxClient{Endpoint: srv.URL, APIToken: "root@pam!test=secret", HTTPClient: srv.Client(), Retry: RetryOptions{MaxAttempts: 1}}
+	got, err := c.GetTaskLog(context.Background(), testUPID, 3)
+	if err != nil {
+		t.Fatalf("GetTaskLog() error = %v", err)
+	}
+	if want := []string{"line 98", "line 99", "line 100"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GetTaskLog() = %q, want %q", got, want)
+	}
+
+	got, err = c.GetTaskLog(context.Background(), testUPID, 1000)
+	if err != nil {
+		t.Fatalf("GetTaskLog() error = %v", err)
+	}
+	if len(got) != total || got[0] != "line 1" {
+		t.Errorf("GetTaskLog() returned %d lines from %q, want the whole log", len(got), got[0])
+	}
+}
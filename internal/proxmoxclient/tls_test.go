@@ -0,0 +1,60 @@
+package proxmoxclient
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTLSOptions(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"data":null}`)
+	}))
+	defer srv.Close()
+
+	sum := sha256.Sum256(srv.Certificate().Raw)
+	fingerprint := formatFingerprint(sum[:])
+	other := sha256.Sum256([]byte("another certificate"))
+	caBundle := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+
+	tests := []struct {
+		name       string
+		opts       TLSOptions
+		wantConfig bool // Whether the options are valid
+		wantOK     bool // Whether the server certificate is accepted
+	}{
+		{name: "system pool rejects test certificate", opts: TLSOptions{}, wantConfig: true},
+		{name: "matching fingerprint", opts: TLSOptions{CertificateFingerprint: fingerprint}, wantConfig: true, wantOK: true},
+		{name: "matching fingerprint without colons", opts: TLSOptions{CertificateFingerprint: hex.EncodeToString(sum[:])}, wantConfig: true, wantOK: true},
+		{name: "mismatching fingerprint", opts: TLSOptions{CertificateFingerprint: formatFingerprint(other[:])}, wantConfig: true},
+		{name: "invalid fingerprint", opts: TLSOptions{CertificateFingerprint: "AB:CD"}},
+		{name: "fingerprint with CA bundle", opts: TLSOptions{CertificateFingerprint: fingerprint, CABundle: caBundle}},
+		{name: "accepted CA bundle", opts: TLSOptions{CABundle: caBundle, ServerName: "example.com"}, wantConfig: true, wantOK: true},
+		{name: "CA bundle for another server name", opts: TLSOptions{CABundle: caBundle, ServerName: "pve.example.org"}, wantConfig: true},
+		{name: "CA bundle without valid PEM", opts: TLSOptions{CABundle: []byte("not a certificate")}},
+		{name: "insecure", opts: TLSOptions{InsecureSkipVerify: true}, wantConfig: true, wantOK: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := newHTTPClient(Options{TLS: tt.opts})
+			if (err == nil) != tt.wantConfig {
+				t.Fatalf("newHTTPClient() error = %v, want valid %v", err, tt.wantConfig)
+			}
+			if err != nil {
+				return
+			}
+			resp, err := client.Get(srv.URL)
+			if err == nil {
+				resp.Body.Close()
+			}
+			if (err == nil) != tt.wantOK {
+				t.Errorf("Get() error = %v, want accepted %v", err, tt.wantOK)
+			}
+		})
+	}
+}
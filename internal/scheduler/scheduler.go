@@ -0,0 +1,117 @@
+// Package scheduler picks a Proxmox node for a new VirtualMachine.
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	proxmoxv1alpha1 "provider-proxmox/api/v1alpha1"
+)
+
+// DefaultMaxCPUUsage is the CPU usage above which a node is not considered.
+const DefaultMaxCPUUsage = 0.9
+
+// Node is a candidate node with its current usage.
+type Node struct {
+	Name     string
+	Online   bool
+	CPU      float64 // CPU usage as a fraction between 0 and 1
+	Mem      int64   // Used memory in bytes
+	MaxMem   int64
+	VMs      int             // Number of VMs placed on the node
+	Storages map[string]bool // Storages available on the node
+	Bridges  map[string]bool // Network bridges configured on the node
+}
+
+// Requirements describe what a node must provide to host the VM.
+type Requirements struct {
+	Memory      int64   // Memory of the VM in bytes
+	MaxCPUUsage float64 // Nodes with a higher CPU usage are skipped
+	Storages    []string
+	Bridges     []string
+}
+
+// Filter returns the nodes able to host a VM with the given requirements,
+// together with the reason each other node was rejected.
+func Filter(nodes []Node, req Requirements) ([]Node, []string) {
+	maxCPU := req.MaxCPUUsage
+	if maxCPU <= 0 {
+		maxCPU = DefaultMaxCPUUsage
+	}
+
+	var eligible []Node
+	var rejected []string
+	for _, n := range nodes {
+		if reason := unfit(n, req, maxCPU); reason != "" {
+			rejected = append(rejected, fmt.Sprintf("%s: %s", n.Name, reason))
+			continue
+		}
+		eligible = append(eligible, n)
+	}
+	return eligible, rejected
+}
+
+func unfit(n Node, req Requirements, maxCPU float64) string {
+	if !n.Online {
+		return "offline"
+	}
+	if n.MaxMem-n.Mem < req.Memory {
+		return "not enough free memory"
+	}
+	if n.CPU > maxCPU {
+		return fmt.Sprintf("CPU usage %.0f%% above limit", n.CPU*100)
+	}
+	for _, s := range req.Storages {
+		if !n.Storages[s] {
+			return fmt.Sprintf("storage %q not available", s)
+		}
+	}
+	for _, b := range req.Bridges {
+		if !n.Bridges[b] {
+			return fmt.Sprintf("bridge %q not configured", b)
+		}
+	}
+	return ""
+}
+
+// Select filters the nodes and ranks the eligible ones according to the policy.
+// Ties are broken by node name so the choice is deterministic.
+func Select(nodes []Node, req Requirements, policy proxmoxv1alpha1.SchedulingPolicy) (string, error) {
+	eligible, rejected := Filter(nodes, req)
+	if len(eligible) == 0 {
+		if len(rejected) == 0 {
+			return "", errors.New("no nodes found in the cluster")
+		}
+		return "", fmt.Errorf("no node can host the VM: %s", strings.Join(rejected, "; "))
+	}
+
+	var score func(Node) float64
+	switch policy {
+	case proxmoxv1alpha1.SchedulingLeastLoaded, "":
+		score = func(n Node) float64 { return (n.CPU + memUsage(n)) / 2 }
+	case proxmoxv1alpha1.SchedulingSpread:
+		score = func(n Node) float64 { return float64(n.VMs) }
+	case proxmoxv1alpha1.SchedulingBinPack:
+		score = func(n Node) float64 { return float64(n.MaxMem - n.Mem - req.Memory) }
+	default:
+		return "", fmt.Errorf("unknown scheduling policy %q", policy)
+	}
+
+	sort.SliceStable(eligible, func(i, j int) bool {
+		si, sj := score(eligible[i]), score(eligible[j])
+		if si != sj {
+			return si < sj
+		}
+		return eligible[i].Name < eligible[j].Name
+	})
+	return eligible[0].Name, nil
+}
+
+func memUsage(n Node) float64 {
+	if n.MaxMem == 0 {
+		return 1
+	}
+	return float64(n.Mem) / float64(n.MaxMem)
+}
@@ -0,0 +1,59 @@
+package scheduler
+
+import (
+	"testing"
+
+	proxmoxv1alpha1 "provider-proxmox/api/v1alpha1"
+)
+
+const gib = 1024 * 1024 * 1024
+
+func TestSelect(t *testing.T) {
+	nodes := []Node{
+		{Name: "pve1", Online: true, CPU: 0.10, Mem: 8 * gib, MaxMem: 32 * gib, VMs: 6, Storages: map[string]bool{"local-lvm": true}, Bridges: map[string]bool{"vmbr0": true}},
+		{Name: "pve2", Online: true, CPU: 0.50, Mem: 28 * gib, MaxMem: 32 * gib, VMs: 2, Storages: map[string]bool{"local-lvm": true}, Bridges: map[string]bool{"vmbr0": true}},
+		{Name: "pve3", Online: true, CPU: 0.20, Mem: 20 * gib, MaxMem: 32 * gib, VMs: 4, Storages: map[string]bool{"local-lvm": true}, Bridges: map[string]bool{"vmbr0": true}},
+		{Name: "pve4", Online: false, CPU: 0, Mem: 0, MaxMem: 64 * gib},
+	}
+	req := Requirements{Memory: 2 * gib, Storages: []string{"local-lvm"}, Bridges: []string{"vmbr0"}}
+
+	cases := map[string]struct {
+		policy proxmoxv1alpha1.SchedulingPolicy
+		req    Requirements
+		want   string
+	}{
+		"LeastLoaded": {policy: proxmoxv1alpha1.SchedulingLeastLoaded, req: req, want: "pve1"},
+		"Spread":      {policy: proxmoxv1alpha1.SchedulingSpread, req: req, want: "pve2"},
+		"BinPack":     {policy: proxmoxv1alpha1.SchedulingBinPack, req: req, want: "pve2"},
+		"BinPackSkipsFullNode": {
+			policy: proxmoxv1alpha1.SchedulingBinPack,
+			req:    Requirements{Memory: 6 * gib},
+			want:   "pve3",
+		},
+		"CPULimit": {
+			policy: proxmoxv1alpha1.SchedulingSpread,
+			req:    Requirements{Memory: gib, MaxCPUUsage: 0.3},
+			want:   "pve3",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := Select(nodes, tc.req, tc.policy)
+			if err != nil {
+				t.Fatalf("Select() error = %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("Select() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSelectNoEligibleNode(t *testing.T) {
+	nodes := []Node{{Name: "pve1", Online: true, MaxMem: 8 * gib, Storages: map[string]bool{"local": true}}}
+
+	if _, err := Select(nodes, Requirements{Storages: []string{"ceph"}}, proxmoxv1alpha1.SchedulingLeastLoaded); err == nil {
+		t.Error("Select() expected an error when no node has the required storage")
+	}
+}